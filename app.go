@@ -1,37 +1,48 @@
 package main
 
 import (
+	"auth/apperr"
 	"auth/db"
+	"auth/logger"
 	"auth/model"
 	"auth/utils"
-	"bytes"
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"image"
-	_ "image/gif"  // 支持GIF格式
-	_ "image/jpeg" // 支持JPEG格式
-	_ "image/png"  // 支持PNG格式
-	"log"
-	"strings"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pquerna/otp"
 
-	gotp "auth/utils/otp_extractor"
-
-	"github.com/makiuchi-d/gozxing"
-	"github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/pquerna/otp/hotp"
 	"github.com/pquerna/otp/totp"
 )
 
+// ErrVaultLocked 保险库未解锁时访问密钥返回的错误
+var ErrVaultLocked = apperr.New(apperr.VaultLocked, "保险库已锁定，请先输入主密码解锁")
+
 // App struct
 type App struct {
 	ctx context.Context
+
+	mu     sync.RWMutex
+	cipher *utils.Cipher // 解锁后由主密码派生的密钥，只保存在内存中，锁定时清除
+
+	previewMu sync.Mutex
+	previews  map[string]importPreview // 等待确认的导入预览，含明文密钥，锁定时清空
+
+	tickerMu     sync.Mutex // 先于 mu 获取
+	tickerCancel context.CancelFunc
+	windowHidden bool
+	codesDirty   atomic.Bool // 账户有变化，计时器需要重新读取
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{}
+	return &App{previews: make(map[string]importPreview)}
 }
 
 // startup is called when the app starts. The context is saved
@@ -40,158 +51,528 @@ func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 }
 
-func (a *App) GetSecretsList() []model.Secret {
-	secrets, err := db.GetSecretsList()
+// IsVaultInitialized 返回是否已经设置过主密码，未设置时首次解锁的密码即为主密码
+func (a *App) IsVaultInitialized() (bool, error) {
+	_, ok, err := db.GetVaultHeader()
+	if err != nil {
+		return false, apperr.Wrap(apperr.DBFailure, "读取保险库信息失败", err)
+	}
+	if ok {
+		return true, nil
+	}
 
+	// 旧版本的数据库没有头信息，但已有数据说明主密码早已确定
+	secrets, err := db.GetSecretsList()
 	if err != nil {
-		log.Println("数据库查询失败", err)
+		return false, apperr.Wrap(apperr.DBFailure, "读取保险库信息失败", err)
+	}
+	if len(secrets) == 0 {
+		return false, nil
+	}
+
+	// 旧版本发布包使用内置的主密码加密，用户需要设置自己的主密码
+	if c := openLegacyVault(secrets); c != nil {
+		c.Wipe()
+		return false, nil
+	}
+	return true, nil
+}
+
+// openLegacyVault 用旧版本内置的主密码尝试打开没有头信息的旧保险库，打不开时返回 nil
+func openLegacyVault(secrets []model.Secret) *utils.Cipher {
+	c, err := utils.LegacyCipher()
+	if err != nil || c == nil {
+		return nil
+	}
+	if !opensAny(c, secrets) {
+		c.Wipe()
 		return nil
 	}
+	return c
+}
 
-	for i := range secrets {
-		decryptedSecret, err := utils.Decrypt(secrets[i].EncryptedSecret)
+// opensAny 返回 c 能否解密其中任意一条记录，用于在没有校验值的旧数据库上验证主密码
+// 旧版本允许不同主密码加密的记录并存，只检查第一条会把正确的主密码误判为错误
+func opensAny(c *utils.Cipher, secrets []model.Secret) bool {
+	for _, secret := range secrets {
+		if _, _, err := c.OpenAccount(secret); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLocked 返回保险库是否处于锁定状态
+func (a *App) IsLocked() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.cipher == nil
+}
+
+// Unlock 校验主密码并解锁保险库，使用旧版派生算法的保险库会在解锁时升级
+func (a *App) Unlock(password string) error {
+	if password == "" {
+		return apperr.New(apperr.InvalidArgument, "主密码不能为空")
+	}
+	logger.Protect(password)
+
+	header, ok, err := db.GetVaultHeader()
+	if err != nil {
+		return apperr.Wrap(apperr.DBFailure, "读取保险库信息失败", err)
+	}
+
+	var c *utils.Cipher
+	if ok {
+		c, err = utils.DeriveCipher(password, header)
 		if err != nil {
-			log.Printf("解密失败: %v, AccountName: %s\n", err, secrets[i].AccountName)
-			continue
+			return apperr.Wrap(apperr.Unsupported, "无法派生密钥", err)
 		}
-		var code string
-		switch secrets[i].AccountType {
-		case 0: // totp
-			code, err = totp.GenerateCode(decryptedSecret, time.Now())
-			if err != nil {
-				log.Printf("生成 TOTP 验证码失败: %v, AccountName: %s\n", err, secrets[i].AccountName)
-				continue
-			}
-		case 1: // steam
-			code, err = utils.GenerateCode(string(decryptedSecret))
-			if err != nil {
-				log.Printf("生成 Steam 验证码失败: %v, AccountName: %s\n", err, secrets[i].AccountName)
-				continue
+		if err := c.CheckVerifier(header.Verifier); err != nil {
+			c.Wipe()
+			return err
+		}
+	} else {
+		// 没有头信息：旧数据库用已有的密钥验证主密码，空数据库直接以该密码初始化
+		header.KDF.Version = model.KDFVersionSHA256
+		c, err = utils.DeriveCipher(password, header)
+		if err != nil {
+			return apperr.Wrap(apperr.Internal, "无法派生密钥", err)
+		}
+
+		secrets, err := db.GetSecretsList()
+		if err != nil {
+			c.Wipe()
+			return apperr.Wrap(apperr.DBFailure, "读取保险库信息失败", err)
+		}
+		if len(secrets) > 0 {
+			if !opensAny(c, secrets) {
+				c.Wipe()
+				// 旧版本发布包的内置主密码能打开时，用户输入的密码作为新的主密码，升级时重新加密
+				c = openLegacyVault(secrets)
+				if c == nil {
+					return utils.ErrWrongPassword
+				}
+				slog.Info("使用旧版本内置的主密码迁移保险库")
 			}
-		default:
-			continue
 		}
+	}
 
+	// 旧版派生算法、没有保险库 ID（密文未绑定到所在行）或账户信息仍为明文的保险库在解锁时升级
+	if header.KDF.Version != model.KDFVersionArgon2id || header.VaultID == "" || !header.EncryptedMetadata {
+		newCipher, err := rewrapVault(c, password)
+		c.Wipe()
 		if err != nil {
-			log.Println("生成验证码失败", err)
-			continue
+			return apperr.Wrap(apperr.DBFailure, "升级保险库失败", err)
 		}
-		secrets[i].Code = code
+		c = newCipher
+	}
 
+	a.mu.Lock()
+	if a.cipher != nil {
+		a.cipher.Wipe()
 	}
+	a.cipher = c
+	a.mu.Unlock()
 
-	return secrets
+	a.restartTicker()
+	return nil
 }
 
-func (a *App) InsertSecret(accountName string, serverName string, secret string, accountType int) error {
-	encryptedSecret, err := utils.Encrypt([]byte(secret))
+// rewrapVault 用新的盐和当前派生算法重新生成密钥，并把所有数据从 old 转为新密钥加密，返回新的 Cipher
+// 已有的保险库 ID 保持不变，旧保险库在这里生成保险库 ID，此后密文都绑定到所在的行，账户名称和服务商也一并加密
+// 无法用 old 解密的账户移入 quarantine 表，不再出现在账户列表和备份中，由界面提示用户删除或保留
+func rewrapVault(old *utils.Cipher, password string) (*utils.Cipher, error) {
+	params, err := utils.NewKDFParams()
 	if err != nil {
-		log.Println("加密失败", err)
-		return err
+		return nil, err
 	}
 
-	log.Printf("accountName：%s,serverName:%s,accountType：%d,加密后密钥: %s\n", accountName, serverName, accountType, encryptedSecret)
-	err = db.InsertSecret(accountType, accountName, serverName, encryptedSecret)
+	vaultID := old.VaultID()
+	if vaultID == "" {
+		vaultID, err = utils.NewVaultID()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	header := model.VaultHeader{KDF: params, VaultID: vaultID, EncryptedMetadata: true}
+	c, err := utils.DeriveCipher(password, header)
 	if err != nil {
-		log.Println("添加失败", err)
+		return nil, err
 	}
 
-	return nil
+	header.Verifier, err = c.NewVerifier()
+	if err != nil {
+		c.Wipe()
+		return nil, err
+	}
+
+	quarantined, err := db.RewrapVault(header, func(secret model.Secret) (model.Secret, bool, error) {
+		opened, plaintext, err := old.OpenAccount(secret)
+		if errors.Is(err, utils.ErrCipherWiped) {
+			return model.Secret{}, false, err
+		}
+		if err == nil {
+			sealed, err := c.SealAccount(opened, plaintext)
+			return sealed, false, err
+		}
 
+		// 密钥无法解密的账户移入 quarantine 表，名称能解密时改用新密钥加密，否则原样保留
+		named, err := old.OpenMetadata(secret)
+		if err != nil {
+			return secret, true, nil
+		}
+		sealed, err := c.SealMetadata(named)
+		return sealed, true, err
+	})
+	if err != nil {
+		c.Wipe()
+		return nil, err
+	}
+	if len(quarantined) > 0 {
+		slog.Warn("部分账户无法用当前主密码解密，已移出账户列表", "ids", quarantined)
+	}
+
+	return c, nil
 }
 
-func (a *App) DeleteSecret(ids []int) error {
-	err := db.DeleteSecret(ids)
+// Lock 锁定保险库、停止验证码计时器并清除内存中的密钥
+func (a *App) Lock() {
+	a.mu.Lock()
+	if a.cipher != nil {
+		a.cipher.Wipe()
+	}
+	a.cipher = nil
+
+	a.clearPreviews()
+	logger.ForgetProtected()
+	a.mu.Unlock()
+
+	a.restartTicker()
+}
+
+// ChangeMasterPassword 校验旧主密码后用新主密码重新加密所有数据，失败时保险库保持原样
+func (a *App) ChangeMasterPassword(oldPassword string, newPassword string) error {
+	if newPassword == "" {
+		return apperr.New(apperr.InvalidArgument, "新主密码不能为空")
+	}
+	logger.Protect(oldPassword, newPassword)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cipher == nil {
+		return ErrVaultLocked
+	}
+
+	if err := checkMasterPassword(oldPassword); err != nil {
+		return err
+	}
+
+	c, err := rewrapVault(a.cipher, newPassword)
 	if err != nil {
-		log.Println("删除失败", err)
+		return apperr.Wrap(apperr.DBFailure, "修改主密码失败", err)
 	}
+
+	a.cipher.Wipe()
+	a.cipher = c
+
 	return nil
 }
-func (a *App) RecognizeQRCode(imgBytes []byte) error {
-	reader := bytes.NewReader(imgBytes)
-	img, format, err := image.Decode(reader)
+
+// checkMasterPassword 校验主密码，用于修改主密码等需要再次确认身份的操作
+func checkMasterPassword(password string) error {
+	header, ok, err := db.GetVaultHeader()
 	if err != nil {
-		log.Printf("图像解码失败: %v, 格式: %s\n", err, format)
-		return fmt.Errorf("无法解码图像: %w", err)
+		return apperr.Wrap(apperr.DBFailure, "读取保险库信息失败", err)
+	}
+	if !ok {
+		return apperr.New(apperr.InvalidArgument, "保险库尚未初始化")
 	}
 
-	// prepare BinaryBitmap
-	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	c, err := utils.DeriveCipher(password, header)
 	if err != nil {
-		return fmt.Errorf("无法创建 BinaryBitmap: %w", err)
+		return apperr.Wrap(apperr.Unsupported, "无法派生密钥", err)
 	}
+	defer c.Wipe()
+	return c.CheckVerifier(header.Verifier)
+}
 
-	// decode image
-	qrReader := qrcode.NewQRCodeReader()
-	result, err := qrReader.Decode(bmp, nil)
+// currentCipher 返回解锁时创建的 Cipher，锁定时返回 ErrVaultLocked
+// 返回后保险库被锁定时 Cipher 已被清除，加解密会返回 utils.ErrCipherWiped
+func (a *App) currentCipher() (*utils.Cipher, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 
+	if a.cipher == nil {
+		return nil, ErrVaultLocked
+	}
+	return a.cipher, nil
+}
+
+func (a *App) GetSecretsList() ([]model.Secret, error) {
+	c, err := a.currentCipher()
 	if err != nil {
-		return fmt.Errorf("解析二维码错误: %w,%s", err, "尝试单独截取二维码部分的图片进行解析")
+		return nil, err
 	}
 
-	fmt.Println(result)
+	secrets, err := db.GetSecretsList()
 
-	switch {
-	case strings.HasPrefix(result.GetText(), "otpauth-migration://"):
-		// 处理 谷歌认证器 otpauth-migration 前缀
+	if err != nil {
+		slog.Error("数据库查询失败", "err", err)
+		return nil, apperr.Wrap(apperr.DBFailure, "读取账户列表失败", err)
+	}
 
-		entries, err := gotp.ExtractOtpFromUrl(result.GetText())
+	// 所有账户使用同一时刻，避免列表中的验证码跨越周期边界
+	now := time.Now()
+	for i := range secrets {
+		secrets[i].Period = codePeriod(secrets[i])
+		secrets[i].ServerTime = now.Unix()
+
+		opened, decryptedSecret, err := c.OpenAccount(secrets[i])
 		if err != nil {
-			return fmt.Errorf("解析谷歌验证器导出码失败: %w", err)
+			slog.Warn("解密失败", "id", secrets[i].ID, "err", err)
+			// 名称同样无法解密，不把密文显示出来
+			secrets[i].AccountName, secrets[i].ServerName = "（无法解密）", ""
+			continue
 		}
-
-		// 打印提取的OTP信息
-		for i, entry := range entries {
-			otpType := 0
-			fmt.Printf("\n%d. 密钥\n", i+1)
-			fmt.Printf("名称:    %s\n", entry.Name)
-			fmt.Printf("密钥:    %s\n", entry.Secret)
-			if entry.Issuer != "" {
-				fmt.Printf("发行者:  %s\n", entry.Issuer)
-			}
-			fmt.Printf("类型:    %s\n", entry.Type)
-			if entry.Type == "hotp" {
-				otpType = 2
-				fmt.Printf("计数器:  %d\n", entry.Counter)
-			}
-
-			err = a.InsertSecret(entry.Name, entry.Issuer, entry.Secret, otpType)
-			if err != nil {
-				return fmt.Errorf("添加账户失败: %w", err)
-			}
+		secrets[i] = opened
+		code, err := generateCode(secrets[i], decryptedSecret, now)
+		if err != nil {
+			slog.Warn("生成验证码失败", "id", secrets[i].ID, "err", err)
+			continue
 		}
+		secrets[i].Code = code
 
-	case strings.HasPrefix(result.GetText(), "otpauth://totp"):
-		// 处理 totp 前缀
-		key, err := otp.NewKeyFromURL(result.GetText())
+		period := int64(secrets[i].Period)
+		if period == 0 {
+			continue
+		}
+		secrets[i].Remaining = uint(period - now.Unix()%period)
+		next := time.Unix(now.Unix()+int64(secrets[i].Remaining), 0)
+		secrets[i].NextCode, err = generateCode(secrets[i], decryptedSecret, next)
 		if err != nil {
-			return fmt.Errorf("获取 OTP 信息失败: %w", err)
+			slog.Warn("生成验证码失败", "id", secrets[i].ID, "err", err)
 		}
+	}
 
-		fmt.Println("Account:", key.AccountName())
-		fmt.Println("Issuer:", key.Issuer())
-		fmt.Println("Secret:", key.Secret())
+	return secrets, nil
+}
 
-		// 添加解析出的验证码信息
-		err = a.InsertSecret(key.AccountName(), key.Issuer(), key.Secret(), 0)
-		if err != nil {
-			return fmt.Errorf("添加账户失败: %w", err)
+// codePeriod 返回账户验证码的有效周期（秒），Steam 固定为 30 秒，HOTP 不随时间变化返回 0
+func codePeriod(secret model.Secret) uint {
+	switch secret.AccountType {
+	case model.AccountTypeSteam:
+		return utils.SteamPeriod
+	case model.AccountTypeHOTP:
+		return 0
+	default:
+		if secret.Period == 0 {
+			return model.DefaultPeriod
 		}
+		return secret.Period
+	}
+}
+
+// generateCode 按账户类型和 OTP 参数生成验证码，HOTP 只使用当前计数器而不推进
+func generateCode(secret model.Secret, decryptedSecret string, t time.Time) (string, error) {
+	algorithm, err := utils.ParseAlgorithm(secret.Algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	digits := otp.Digits(secret.Digits)
+	if digits == 0 {
+		digits = model.DefaultDigits
+	}
 
-	//case strings.HasPrefix(result.GetText(), "yet-another-prefix://"):
-	//	// 处理 yet-another-prefix 前缀
+	switch secret.AccountType {
+	case model.AccountTypeTOTP:
+		return totp.GenerateCodeCustom(decryptedSecret, t, totp.ValidateOpts{
+			Period:    secret.Period,
+			Digits:    digits,
+			Algorithm: algorithm,
+		})
+	case model.AccountTypeSteam:
+		return utils.GenerateCodeWithTime(decryptedSecret, t)
+	case model.AccountTypeHOTP:
+		return hotp.GenerateCodeCustom(decryptedSecret, secret.Counter, hotp.ValidateOpts{
+			Digits:    digits,
+			Algorithm: algorithm,
+		})
 	default:
-		return fmt.Errorf("二维码格式错误: %w", err)
+		return "", apperr.New(apperr.Unsupported, fmt.Sprintf("未知的账户类型: %d", secret.AccountType))
 	}
+}
+
+func (a *App) InsertSecret(accountName string, serverName string, secret string, accountType int) error {
+	return a.insertSecret(model.Secret{
+		AccountName: accountName,
+		ServerName:  serverName,
+		AccountType: uint(accountType),
+		Algorithm:   model.DefaultAlgorithm,
+		Digits:      model.DefaultDigits,
+		Period:      model.DefaultPeriod,
+	}, secret)
+}
 
+// insertSecret 校验并加密明文密钥后写入数据库，secret 中除 EncryptedSecret 外的字段需由调用方填好
+func (a *App) insertSecret(secret model.Secret, plaintext string) error {
+	// 加密到写入期间持有读锁，避免与修改主密码交错导致写入旧密钥加密的数据
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.cipher == nil {
+		return ErrVaultLocked
+	}
+
+	secret, plaintext, err := normalizeSecret(secret, plaintext)
+	if err != nil {
+		return err
+	}
+
+	// 手动添加时不覆盖已有账户，查重规则与导入相同
+	existing, err := newImportSession(a.cipher, DuplicateSkip)
+	if err != nil {
+		return err
+	}
+	if existing.findDuplicate(secret, plaintext) >= 0 {
+		return apperr.New(apperr.DuplicateAccount, "已存在密钥相同或服务商和账户名称都相同的账户")
+	}
+
+	id, err := db.InsertSecret(db.PendingSecret{Secret: secret, Seal: sealWith(a.cipher, plaintext)})
+	if err != nil {
+		slog.Error("添加失败", "err", err)
+		return apperr.Wrap(apperr.DBFailure, "添加账户失败", err)
+	}
+	slog.Info("添加账户", "id", id, "type", secret.AccountType)
+
+	a.invalidateCodes()
 	return nil
+
+}
+
+// sealWith 返回在行 ID 确定后加密明文密钥和账户信息的 db.SealFunc
+func sealWith(c *utils.Cipher, plaintext string) db.SealFunc {
+	return func(secret model.Secret) (model.Secret, error) {
+		sealed, err := c.SealAccount(secret, plaintext)
+		if err != nil {
+			slog.Error("加密失败", "err", err)
+			return model.Secret{}, apperr.Wrap(apperr.Internal, "加密失败", err)
+		}
+		return sealed, nil
+	}
+}
+
+// NextHOTPCode 推进 HOTP 账户的计数器并返回新的验证码
+func (a *App) NextHOTPCode(id int) (string, error) {
+	c, err := a.currentCipher()
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := db.IncrementCounter(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", apperr.New(apperr.NotFound, "HOTP 账户不存在")
+	}
+	if err != nil {
+		return "", apperr.Wrap(apperr.DBFailure, "更新计数器失败", err)
+	}
+
+	_, decryptedSecret, err := c.OpenAccount(secret)
+	if err != nil {
+		return "", apperr.Wrap(apperr.Internal, "解密失败", err)
+	}
+
+	code, err := generateCode(secret, decryptedSecret, time.Now())
+	if err != nil {
+		return "", apperr.Wrap(apperr.InvalidSecret, "生成 HOTP 验证码失败", err)
+	}
+
+	return code, nil
+}
+
+func (a *App) DeleteSecret(ids []int) error {
+	if _, err := a.currentCipher(); err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return apperr.New(apperr.InvalidArgument, "请选择要删除的账户")
+	}
+
+	err := db.DeleteSecret(ids)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperr.New(apperr.NotFound, "账户不存在")
+	}
+	if err != nil {
+		slog.Error("删除失败", "err", err)
+		return apperr.Wrap(apperr.DBFailure, "删除账户失败", err)
+	}
+	a.invalidateCodes()
+	return nil
+}
+
+// RecognizeQRCode 识别图片中所有二维码的 otpauth 链接并导入，mode 为重复账户的处理方式
+func (a *App) RecognizeQRCode(imgBytes []byte, mode string) (ImportSummary, error) {
+	if _, err := a.currentCipher(); err != nil {
+		return ImportSummary{}, err
+	}
+
+	texts, strategy, err := decodeQRCodes(imgBytes)
+	if err != nil {
+		return ImportSummary{}, err
+	}
+
+	parsed, err := parseQRTexts(texts)
+	if err != nil {
+		return ImportSummary{}, err
+	}
+
+	summary, err := a.runImport(mode, func(s *importSession) error {
+		for _, account := range parsed.accounts {
+			if _, err := s.add(account.secret, account.plaintext); err != nil {
+				return accountError(account.secret.AccountName, err)
+			}
+		}
+		return nil
+	})
+	summary.Warnings = parsed.warnings
+	summary.Strategy = strategy
+	return summary, err
 }
 
 func (a *App) UpdateSecret(id int, accountName string, serverName string, accountType int) error {
-	err := db.UpdateSecret(id, accountName, serverName, accountType)
+	// 与 insertSecret 相同，重新加密到写入期间持有读锁，避免与修改主密码交错
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.cipher == nil {
+		return ErrVaultLocked
+	}
+	c := a.cipher
+
+	err := db.UpdateSecret(id, accountName, serverName, accountType, func(old, updated model.Secret) (model.Secret, error) {
+		_, plaintext, err := c.OpenAccount(old)
+		if err != nil {
+			return model.Secret{}, apperr.Wrap(apperr.Internal, "解密失败", err)
+		}
+
+		// 修改类型后密钥按新类型解释，Base32 密钥改为 Steam 或反过来都会导致每次刷新都生成失败
+		updated, plaintext, err = normalizeSecret(updated, plaintext)
+		if err != nil && old.AccountType != updated.AccountType {
+			return model.Secret{}, &apperr.Error{Code: apperr.InvalidSecret, Message: "已保存的密钥不能用于所选的账户类型", Err: err}
+		}
+		if err != nil {
+			return model.Secret{}, err
+		}
+		return sealWith(c, plaintext)(updated)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperr.New(apperr.NotFound, "账户不存在")
+	}
 	if err != nil {
-		log.Println("编辑失败", err)
+		slog.Error("编辑失败", "id", id, "err", err)
+		return apperr.Wrap(apperr.DBFailure, "编辑账户失败", err)
 	}
+	a.invalidateCodes()
 	return nil
 
 }
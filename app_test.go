@@ -0,0 +1,103 @@
+package main
+
+import (
+	"auth/apperr"
+	"auth/db"
+	"auth/model"
+	"testing"
+	"time"
+)
+
+// newTestApp 在临时目录中创建数据库，以主密码 pw 解锁并添加一个 TOTP 账户
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+	if err := db.InitDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.DB.Close()
+		db.DB = nil
+	})
+
+	a := NewApp()
+	if err := a.Unlock("pw"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.InsertSecret("alice", "GitHub", "JBSWY3DPEHPK3PXP", model.AccountTypeTOTP); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestErrorCodes(t *testing.T) {
+	a := newTestApp(t)
+
+	tests := []struct {
+		name string
+		run  func() error
+		want apperr.Code
+	}{
+		{"解锁时主密码错误", func() error { return NewApp().Unlock("wrong") }, apperr.WrongPassword},
+		{"修改主密码时原密码错误", func() error { return a.ChangeMasterPassword("wrong", "new") }, apperr.WrongPassword},
+		{"显示二维码时主密码错误", func() error { _, err := a.GetAccountQRCode(1, "wrong"); return err }, apperr.WrongPassword},
+		{"服务商和账户名称相同", func() error { return a.InsertSecret("alice", "GitHub", "GEZDGNBVGY3TQOJQ", model.AccountTypeTOTP) }, apperr.DuplicateAccount},
+		{"密钥相同", func() error { return a.InsertSecret("bob", "GitLab", "JBSWY3DPEHPK3PXP", model.AccountTypeTOTP) }, apperr.DuplicateAccount},
+		{"删除不存在的账户", func() error { return a.DeleteSecret([]int{999}) }, apperr.NotFound},
+		{"编辑不存在的账户", func() error { return a.UpdateSecret(999, "bob", "GitLab", model.AccountTypeTOTP) }, apperr.NotFound},
+		{"推进不存在的 HOTP 账户", func() error { _, err := a.NextHOTPCode(999); return err }, apperr.NotFound},
+		{"导出不存在的账户", func() error { _, err := a.ExportMigrationQRCodes([]int{1, 1, 999}, "pw"); return err }, apperr.NotFound},
+		{"提交不存在的导入预览", func() error { _, err := a.CommitImport("missing", []int{0}, DuplicateSkip); return err }, apperr.NotFound},
+		{"未知的账户类型", func() error { return a.InsertSecret("carol", "Example", "GEZDGNBVGY3TQOJQ", 9) }, apperr.Unsupported},
+		{"无法解码的图片", func() error { _, err := a.RecognizeQRCode([]byte("not an image"), DuplicateSkip); return err }, apperr.Unsupported},
+		{"密钥格式错误", func() error { return a.InsertSecret("dave", "Example", "not base32!", model.AccountTypeTOTP) }, apperr.InvalidSecret},
+		{"新主密码为空", func() error { return a.ChangeMasterPassword("pw", "") }, apperr.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			if err == nil {
+				t.Fatalf("期望错误码 %s，实际没有返回错误", tt.want)
+			}
+			if got := apperr.CodeOf(err); got != tt.want {
+				t.Errorf("错误码为 %s，期望 %s: %v", got, tt.want, err)
+			}
+		})
+	}
+
+	t.Run("锁定后", func(t *testing.T) {
+		a.Lock()
+		if _, err := a.GetSecretsList(); apperr.CodeOf(err) != apperr.VaultLocked {
+			t.Errorf("错误码为 %s，期望 %s: %v", apperr.CodeOf(err), apperr.VaultLocked, err)
+		}
+	})
+}
+
+func TestCommitImportKeepsPreviewOnFailure(t *testing.T) {
+	a := newTestApp(t)
+	a.previews["token"] = importPreview{
+		accounts: []uriAccount{
+			{secret: model.Secret{AccountName: "bob", AccountType: model.AccountTypeTOTP}, plaintext: "not base32!"},
+			{secret: model.Secret{AccountName: "carol", AccountType: model.AccountTypeTOTP}, plaintext: "GEZDGNBVGY3TQOJQ"},
+		},
+		createdAt: time.Now(),
+	}
+
+	if _, err := a.CommitImport("token", []int{0, 1}, DuplicateSkip); apperr.CodeOf(err) != apperr.InvalidSecret {
+		t.Fatalf("导入无效密钥返回 %v", err)
+	}
+	if preview, ok := a.previews["token"]; !ok || preview.committing {
+		t.Fatal("导入失败后预览应保留并可再次提交")
+	}
+
+	summary, err := a.CommitImport("token", []int{1}, DuplicateSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Added != 1 {
+		t.Errorf("新增 %d 个账户", summary.Added)
+	}
+	if _, err := a.CommitImport("token", []int{1}, DuplicateSkip); apperr.CodeOf(err) != apperr.NotFound {
+		t.Errorf("导入成功后再次提交返回 %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package apperr
+
+import "errors"
+
+// Code 错误码，通过 Wails 绑定原样传给前端，前端据此决定提示内容
+type Code string
+
+const (
+	VaultLocked      Code = "VaultLocked"      // 保险库未解锁
+	WrongPassword    Code = "WrongPassword"    // 主密码或导入文件密码错误
+	PasswordRequired Code = "PasswordRequired" // 导入的文件已加密，需要提供密码
+	DuplicateAccount Code = "DuplicateAccount" // 账户已存在
+	InvalidSecret    Code = "InvalidSecret"    // 密钥格式错误或无法生成验证码
+	InvalidArgument  Code = "InvalidArgument"  // 其他参数错误
+	NotFound         Code = "NotFound"         // 账户不存在
+	Unsupported      Code = "Unsupported"      // 不支持的格式或账户类型
+	DBFailure        Code = "DBFailure"        // 数据库读写失败
+	Internal         Code = "Internal"         // 加解密等内部错误
+)
+
+// Error 带错误码的错误，Err 为底层原因，只用于日志和 errors.Is/As
+type Error struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+	Err     error  `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is 错误码相同即视为同一种错误，方便用 errors.Is 和预定义的错误比较
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// New 创建带错误码的错误
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap 为底层错误附加错误码和说明，err 已带错误码时原样返回
+func Wrap(code Code, message string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+// CodeOf 返回错误的错误码，没有错误码的错误视为 Internal
+func CodeOf(err error) Code {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return Internal
+}
+
+// Format 作为 Wails 的 ErrorFormatter，把错误转换为前端收到的 {code, message} 对象
+func Format(err error) any {
+	return &Error{Code: CodeOf(err), Message: err.Error()}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"auth/apperr"
+	"auth/db"
+	"auth/logger"
+	"auth/model"
+	"auth/utils"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ExportBackup 把所有账户用备份密码加密后导出到 path，备份与当前主密码无关，可在其他设备上恢复
+func (a *App) ExportBackup(path string, password string) error {
+	if password == "" {
+		return apperr.New(apperr.InvalidArgument, "备份密码不能为空")
+	}
+	logger.Protect(password)
+
+	c, err := a.currentCipher()
+	if err != nil {
+		return err
+	}
+
+	secrets, err := db.GetSecretsList()
+	if err != nil {
+		return apperr.Wrap(apperr.DBFailure, "读取账户失败", err)
+	}
+
+	payload := utils.BackupPayload{
+		CreatedAt: time.Now().UTC(),
+		Secrets:   make([]utils.BackupEntry, 0, len(secrets)),
+	}
+	for _, secret := range secrets {
+		secret, plaintext, err := c.OpenAccount(secret)
+		if err != nil {
+			return apperr.Wrap(apperr.Internal, fmt.Sprintf("解密账户 %d 失败", secret.ID), err)
+		}
+
+		payload.Secrets = append(payload.Secrets, utils.BackupEntry{
+			AccountName: secret.AccountName,
+			ServerName:  secret.ServerName,
+			AccountType: secret.AccountType,
+			Secret:      plaintext,
+			Algorithm:   secret.Algorithm,
+			Digits:      secret.Digits,
+			Period:      secret.Period,
+			Counter:     secret.Counter,
+		})
+	}
+
+	data, err := utils.SealBackup(payload, password)
+	if err != nil {
+		return apperr.Wrap(apperr.Internal, "加密备份失败", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return apperr.Wrap(apperr.Internal, "写入备份文件失败", err)
+	}
+
+	return nil
+}
+
+// RestoreBackup 解密 path 处的备份文件，用当前主密码重新加密后写入
+// mode 为重复账户的处理方式，返回新增、跳过和覆盖的账户数量
+func (a *App) RestoreBackup(path string, password string, mode string) (ImportSummary, error) {
+	logger.Protect(password)
+	if _, err := a.currentCipher(); err != nil {
+		return ImportSummary{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ImportSummary{}, apperr.Wrap(apperr.InvalidArgument, "读取备份文件失败", err)
+	}
+
+	payload, err := utils.OpenBackup(data, password)
+	if err != nil {
+		return ImportSummary{}, err
+	}
+
+	return a.runImport(mode, func(s *importSession) error {
+		for _, entry := range payload.Secrets {
+			_, err := s.add(model.Secret{
+				AccountName: entry.AccountName,
+				ServerName:  entry.ServerName,
+				AccountType: entry.AccountType,
+				Algorithm:   entry.Algorithm,
+				Digits:      entry.Digits,
+				Period:      entry.Period,
+				Counter:     entry.Counter,
+			}, entry.Secret)
+			if err != nil {
+				return accountError(entry.AccountName, err)
+			}
+		}
+		return nil
+	})
+}
@@ -1,9 +1,2 @@
-Get-Content .env | ForEach-Object {
-    $parts = $_ -split '=', 2
-    if ($parts.Length -eq 2) {
-        $envName = $parts[0].Trim()
-        $envValue = $parts[1].Trim()
-        [System.Environment]::SetEnvironmentVariable($envName, $envValue)
-    }
-}
-wails build -clean -ldflags="-X auth/utils.embeddedMasterPassword=$env:MASTER_PASSWORD"
+# LEGACY_MASTER_PASSWORD 为旧版本发布包内置的主密码，只用于迁移旧版本创建的保险库，本地构建不需要设置
+wails build -clean -ldflags="-X auth/utils.legacyMasterPassword=$env:LEGACY_MASTER_PASSWORD"
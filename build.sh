@@ -1,6 +1,3 @@
 #!/bin/bash
-set -o allexport
-source .env
-set +o allexport
-
-wails build -clean -ldflags="-X auth/utils.embeddedMasterPassword=$MASTER_PASSWORD"
+# LEGACY_MASTER_PASSWORD 为旧版本发布包内置的主密码，只用于迁移旧版本创建的保险库，本地构建不需要设置
+wails build -clean -ldflags="-X auth/utils.legacyMasterPassword=$LEGACY_MASTER_PASSWORD"
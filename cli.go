@@ -0,0 +1,682 @@
+package main
+
+import (
+	"auth/apperr"
+	"auth/model"
+	"auth/utils"
+	"auth/utils/aegis"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/term"
+)
+
+// 命令行模式下读取主密码和导入/导出密码的环境变量
+const (
+	passwordEnv       = "EUTHENTICATOR_PASSWORD"
+	newPasswordEnv    = "EUTHENTICATOR_NEW_PASSWORD"
+	backupPasswordEnv = "EUTHENTICATOR_BACKUP_PASSWORD"
+)
+
+// 命令行退出码
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// errUsage 参数错误，对应退出码 exitUsage
+var errUsage = errors.New("参数错误")
+
+// cliCommand 一个子命令
+type cliCommand struct {
+	usage string
+	run   func(c *cli, args []string) error
+}
+
+var cliCommands = map[string]cliCommand{
+	"list":   {"list [--json]", (*cli).list},
+	"code":   {"code [--json] <ID 或名称>", (*cli).code},
+	"add":    {"add [--json] --name 名称 [--issuer 服务商] [--type totp|hotp|steam] [--algorithm SHA1] [--digits 6] [--period 30] [--counter 0]", (*cli).add},
+	"import": {"import [--json] [--on-duplicate skip|overwrite|keep] <文件|->", (*cli).importFile},
+	"export": {"export [--json] <文件>", (*cli).export},
+	"delete": {"delete [--json] <ID>...", (*cli).delete},
+	"passwd": {"passwd [--json]", (*cli).passwd},
+}
+
+// cli 命令行模式的运行状态
+type cli struct {
+	app    *App
+	json   bool
+	stdin  *bufio.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// runCLI 执行子命令并返回进程退出码，与图形界面共用同一个数据库
+func runCLI(args []string) int {
+	c := &cli{
+		app:    NewApp(),
+		stdin:  bufio.NewReader(os.Stdin),
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+
+	name := args[0]
+	command, ok := cliCommands[name]
+	if !ok {
+		c.printUsage()
+		if name == "help" {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	err := command.run(c, args[1:])
+	if err == nil {
+		return exitOK
+	}
+
+	if c.json {
+		// 与图形界面相同，输出 {code, message}，参数错误没有错误码，按 InvalidArgument 输出
+		jsonErr := err
+		if errors.Is(err, errUsage) {
+			jsonErr = apperr.New(apperr.InvalidArgument, err.Error())
+		}
+		_ = json.NewEncoder(c.stderr).Encode(apperr.Format(jsonErr))
+	} else {
+		fmt.Fprintln(c.stderr, "错误:", err)
+	}
+
+	if errors.Is(err, errUsage) {
+		fmt.Fprintln(c.stderr, "用法:", os.Args[0], command.usage)
+		return exitUsage
+	}
+	return exitError
+}
+
+func (c *cli) printUsage() {
+	fmt.Fprintf(c.stderr, "用法: %s [--data-dir 目录] <命令> [参数]\n\n命令:\n", os.Args[0])
+	for _, name := range []string{"list", "code", "add", "import", "export", "delete", "passwd"} {
+		fmt.Fprintln(c.stderr, "  "+cliCommands[name].usage)
+	}
+	fmt.Fprintf(c.stderr, "\n主密码从环境变量 %s 读取，未设置时在终端中输入\n", passwordEnv)
+}
+
+// newFlagSet 创建子命令的参数解析器，所有子命令都支持 --json
+func (c *cli) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&c.json, "json", false, "以 JSON 格式输出")
+	return fs
+}
+
+func (c *cli) parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return nil
+}
+
+// unlock 用环境变量或终端输入的主密码解锁，保险库尚未初始化时会要求确认新密码
+func (c *cli) unlock() error {
+	initialized, err := c.app.IsVaultInitialized()
+	if err != nil {
+		return err
+	}
+
+	password := os.Getenv(passwordEnv)
+	if password == "" {
+		password, err = c.readPassword("主密码: ")
+		if err != nil {
+			return err
+		}
+
+		if !initialized {
+			confirm, err := c.readPassword("确认主密码: ")
+			if err != nil {
+				return err
+			}
+			if confirm != password {
+				return apperr.New(apperr.InvalidArgument, "两次输入的主密码不一致")
+			}
+		}
+	}
+
+	if err := c.app.Unlock(password); err != nil {
+		return err
+	}
+
+	if quarantined, err := c.app.GetQuarantinedAccounts(); err == nil && len(quarantined) > 0 {
+		fmt.Fprintf(c.stderr, "警告: %d 个账户无法用当前主密码解密，已移出账户列表，可在图形界面中删除\n", len(quarantined))
+	}
+	return nil
+}
+
+// readPassword 从终端读取密码且不回显，标准输入不是终端时按行读取
+func (c *cli) readPassword(prompt string) (string, error) {
+	fmt.Fprint(c.stderr, prompt)
+	defer fmt.Fprintln(c.stderr)
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		password, err := term.ReadPassword(fd)
+		if err != nil {
+			return "", fmt.Errorf("读取密码失败: %w", err)
+		}
+		return string(password), nil
+	}
+
+	return c.readLine()
+}
+
+func (c *cli) readLine() (string, error) {
+	line, err := c.stdin.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", fmt.Errorf("读取输入失败: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// backupPassword 读取导入/导出文件的密码
+func (c *cli) backupPassword(prompt string) (string, error) {
+	if password := os.Getenv(backupPasswordEnv); password != "" {
+		return password, nil
+	}
+	return c.readPassword(prompt)
+}
+
+// printJSON 以 JSON 格式输出结果
+func (c *cli) printJSON(v any) error {
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// cliAccount 命令行输出的账户信息
+type cliAccount struct {
+	ID          uint   `json:"id"`
+	Issuer      string `json:"issuer"`
+	AccountName string `json:"account_name"`
+	Type        string `json:"type"`
+	Code        string `json:"code"`
+	NextCode    string `json:"next_code,omitempty"`
+	Period      uint   `json:"period,omitempty"`
+	Remaining   uint   `json:"remaining,omitempty"` // 验证码剩余有效秒数，HOTP 没有
+}
+
+func newCLIAccount(secret model.Secret) cliAccount {
+	return cliAccount{
+		ID:          secret.ID,
+		Issuer:      secret.ServerName,
+		AccountName: secret.AccountName,
+		Type:        accountTypeName(secret.AccountType),
+		Code:        secret.Code,
+		NextCode:    secret.NextCode,
+		Period:      secret.Period,
+		Remaining:   secret.Remaining,
+	}
+}
+
+func accountTypeName(accountType uint) string {
+	switch accountType {
+	case model.AccountTypeTOTP:
+		return "totp"
+	case model.AccountTypeSteam:
+		return "steam"
+	case model.AccountTypeHOTP:
+		return "hotp"
+	default:
+		return strconv.Itoa(int(accountType))
+	}
+}
+
+func parseAccountType(name string) (uint, error) {
+	switch strings.ToLower(name) {
+	case "totp":
+		return model.AccountTypeTOTP, nil
+	case "steam":
+		return model.AccountTypeSteam, nil
+	case "hotp":
+		return model.AccountTypeHOTP, nil
+	default:
+		return 0, fmt.Errorf("%w: 未知的账户类型 %s", errUsage, name)
+	}
+}
+
+func (c *cli) list(args []string) error {
+	fs := c.newFlagSet("list")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if err := c.unlock(); err != nil {
+		return err
+	}
+
+	secrets, err := c.app.GetSecretsList()
+	if err != nil {
+		return err
+	}
+
+	accounts := make([]cliAccount, 0, len(secrets))
+	for _, secret := range secrets {
+		accounts = append(accounts, newCLIAccount(secret))
+	}
+
+	if c.json {
+		return c.printJSON(accounts)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\t服务商\t账户\t类型\t验证码\t剩余")
+	for _, account := range accounts {
+		remaining := "-"
+		if account.Remaining > 0 {
+			remaining = fmt.Sprintf("%ds", account.Remaining)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", account.ID, account.Issuer, account.AccountName, account.Type, account.Code, remaining)
+	}
+	return w.Flush()
+}
+
+// code 输出单个账户的验证码，HOTP 账户会推进计数器
+func (c *cli) code(args []string) error {
+	fs := c.newFlagSet("code")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%w: 需要指定一个账户", errUsage)
+	}
+	if err := c.unlock(); err != nil {
+		return err
+	}
+
+	secrets, err := c.app.GetSecretsList()
+	if err != nil {
+		return err
+	}
+
+	secret, err := findAccount(secrets, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if secret.AccountType == model.AccountTypeHOTP {
+		secret.Code, err = c.app.NextHOTPCode(int(secret.ID))
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.json {
+		return c.printJSON(newCLIAccount(secret))
+	}
+	fmt.Fprintln(c.stdout, secret.Code)
+	return nil
+}
+
+// findAccount 按 ID 精确匹配，或按 "服务商:账户" 不区分大小写地模糊匹配，匹配到多个时报错
+func findAccount(secrets []model.Secret, query string) (model.Secret, error) {
+	if id, err := strconv.Atoi(query); err == nil {
+		for _, secret := range secrets {
+			if secret.ID == uint(id) {
+				return secret, nil
+			}
+		}
+	}
+
+	query = strings.ToLower(query)
+	var matches []model.Secret
+	for _, secret := range secrets {
+		label := strings.ToLower(secret.ServerName + ":" + secret.AccountName)
+		if label == query || strings.ToLower(secret.AccountName) == query {
+			return secret, nil
+		}
+		if strings.Contains(label, query) {
+			matches = append(matches, secret)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return model.Secret{}, apperr.New(apperr.NotFound, fmt.Sprintf("没有找到匹配 %q 的账户", query))
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, 0, len(matches))
+		for _, m := range matches {
+			names = append(names, fmt.Sprintf("%d(%s:%s)", m.ID, m.ServerName, m.AccountName))
+		}
+		return model.Secret{}, apperr.New(apperr.InvalidArgument, fmt.Sprintf("%q 匹配到多个账户，请使用 ID: %s", query, strings.Join(names, ", ")))
+	}
+}
+
+// add 添加账户，密钥从终端或标准输入读取，避免出现在命令历史中
+func (c *cli) add(args []string) error {
+	fs := c.newFlagSet("add")
+	name := fs.String("name", "", "账户名称")
+	issuer := fs.String("issuer", "", "服务商")
+	accountType := fs.String("type", "totp", "账户类型")
+	algorithm := fs.String("algorithm", model.DefaultAlgorithm, "算法")
+	digits := fs.Uint("digits", model.DefaultDigits, "验证码位数")
+	period := fs.Uint("period", model.DefaultPeriod, "周期（秒）")
+	counter := fs.Uint64("counter", 0, "HOTP 计数器")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return fmt.Errorf("%w: 需要指定 --name", errUsage)
+	}
+
+	typ, err := parseAccountType(*accountType)
+	if err != nil {
+		return err
+	}
+	if _, err := utils.ParseAlgorithm(*algorithm); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	if err := c.unlock(); err != nil {
+		return err
+	}
+
+	plaintext, err := c.readPassword("密钥: ")
+	if err != nil {
+		return err
+	}
+
+	// 密钥的格式由 insertSecret 统一校验
+	err = c.app.insertSecret(model.Secret{
+		AccountName: *name,
+		ServerName:  *issuer,
+		AccountType: typ,
+		Algorithm:   strings.ToUpper(*algorithm),
+		Digits:      *digits,
+		Period:      *period,
+		Counter:     *counter,
+	}, plaintext)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return c.printJSON(map[string]int{"added": 1})
+	}
+	fmt.Fprintln(c.stdout, "已添加账户", *name)
+	return nil
+}
+
+// importFile 根据文件内容识别备份文件、Aegis 导出文件或二维码图片并导入
+func (c *cli) importFile(args []string) error {
+	fs := c.newFlagSet("import")
+	mode := fs.String("on-duplicate", DuplicateSkip, "已存在相同账户时的处理方式: skip 跳过、overwrite 覆盖、keep 保留两者")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%w: 需要指定一个文件", errUsage)
+	}
+	path := fs.Arg(0)
+
+	// "-" 表示从标准输入读取，便于从密码管理器等直接传入 otpauth 链接
+	// 标准输入被链接占用时无法再输入主密码，需要通过环境变量提供
+	if path == "-" && os.Getenv(passwordEnv) == "" && !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("%w: 从标准输入导入时需要通过环境变量 %s 提供主密码", errUsage, passwordEnv)
+	}
+
+	// 先解锁再读取，在终端中输入链接时主密码提示不会被链接占用
+	if err := c.unlock(); err != nil {
+		return err
+	}
+
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(c.stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return apperr.Wrap(apperr.InvalidArgument, "读取文件失败", err)
+	}
+
+	if text := bytes.TrimSpace(data); bytes.HasPrefix(text, []byte("otpauth")) {
+		return c.importURIs(string(text), *mode)
+	}
+	if path == "-" {
+		return fmt.Errorf("%w: 标准输入只支持 otpauth 链接", errUsage)
+	}
+
+	var summary ImportSummary
+	var header struct {
+		Format string `json:"format"`
+	}
+	switch {
+	case json.Unmarshal(data, &header) == nil && header.Format == utils.BackupFormat:
+		password, err := c.backupPassword("备份密码: ")
+		if err != nil {
+			return err
+		}
+		summary, err = c.app.RestoreBackup(path, password, *mode)
+		if err != nil {
+			return err
+		}
+
+	case json.Valid(data):
+		summary, err = c.app.ImportAegis(data, "", *mode)
+		if errors.Is(err, aegis.ErrPasswordRequired) {
+			var password string
+			password, err = c.backupPassword("Aegis 导出密码: ")
+			if err != nil {
+				return err
+			}
+			summary, err = c.app.ImportAegis(data, password, *mode)
+		}
+		if err != nil {
+			return err
+		}
+
+	default:
+		summary, err = c.app.RecognizeQRCode(data, *mode)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.json {
+		return c.printJSON(summary)
+	}
+	c.printSummary(summary)
+	return nil
+}
+
+// printSummary 输出导入的统计结果
+func (c *cli) printSummary(summary ImportSummary) {
+	fmt.Fprintf(c.stdout, "新增 %d 个账户，跳过 %d 个重复账户，覆盖 %d 个已有账户\n", summary.Added, summary.Skipped, summary.Updated)
+	if summary.Strategy != "" {
+		fmt.Fprintln(c.stderr, "二维码识别方式: "+summary.Strategy)
+	}
+	for _, warning := range summary.Warnings {
+		fmt.Fprintln(c.stderr, "警告: "+warning)
+	}
+}
+
+// importURIs 逐行导入 otpauth 链接并输出每一行的结果，有任意一行失败时返回错误
+func (c *cli) importURIs(text string, mode string) error {
+	report, err := c.app.ImportURI(text, mode)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range report.Lines {
+		if result.Error != "" {
+			failed++
+		}
+	}
+
+	if c.json {
+		if err := c.printJSON(report); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range report.Lines {
+			for _, name := range result.Added {
+				fmt.Fprintf(c.stdout, "第 %d 行: 已添加账户 %s\n", result.Line, name)
+			}
+			for _, name := range result.Skipped {
+				fmt.Fprintf(c.stdout, "第 %d 行: 账户 %s 已存在，已跳过\n", result.Line, name)
+			}
+			for _, name := range result.Updated {
+				fmt.Fprintf(c.stdout, "第 %d 行: 已覆盖账户 %s\n", result.Line, name)
+			}
+			if result.Error != "" {
+				fmt.Fprintf(c.stdout, "第 %d 行: %s\n", result.Line, result.Error)
+			}
+		}
+		c.printSummary(report.Summary)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d 行链接导入失败", failed)
+	}
+	return nil
+}
+
+// export 导出加密备份文件
+func (c *cli) export(args []string) error {
+	fs := c.newFlagSet("export")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%w: 需要指定一个文件", errUsage)
+	}
+	if err := c.unlock(); err != nil {
+		return err
+	}
+
+	password := os.Getenv(backupPasswordEnv)
+	if password == "" {
+		var err error
+		password, err = c.readPassword("备份密码: ")
+		if err != nil {
+			return err
+		}
+		confirm, err := c.readPassword("确认备份密码: ")
+		if err != nil {
+			return err
+		}
+		if confirm != password {
+			return apperr.New(apperr.InvalidArgument, "两次输入的备份密码不一致")
+		}
+	}
+
+	if err := c.app.ExportBackup(fs.Arg(0), password); err != nil {
+		return err
+	}
+
+	if c.json {
+		return c.printJSON(map[string]string{"path": fs.Arg(0)})
+	}
+	fmt.Fprintln(c.stdout, "已导出备份到", fs.Arg(0))
+	return nil
+}
+
+func (c *cli) delete(args []string) error {
+	fs := c.newFlagSet("delete")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("%w: 需要指定至少一个 ID", errUsage)
+	}
+
+	ids := make([]int, 0, fs.NArg())
+	for _, arg := range fs.Args() {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("%w: 无效的 ID %s", errUsage, arg)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := c.unlock(); err != nil {
+		return err
+	}
+	if err := c.app.DeleteSecret(ids); err != nil {
+		return err
+	}
+
+	if c.json {
+		return c.printJSON(map[string][]int{"deleted": ids})
+	}
+	fmt.Fprintf(c.stdout, "已删除 %d 个账户\n", len(ids))
+	return nil
+}
+
+// passwd 修改主密码，新密码从环境变量读取，未设置时在终端中输入两次
+func (c *cli) passwd(args []string) error {
+	fs := c.newFlagSet("passwd")
+	if err := c.parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("%w: 不接受额外参数", errUsage)
+	}
+
+	initialized, err := c.app.IsVaultInitialized()
+	if err != nil {
+		return err
+	}
+	if !initialized {
+		return apperr.New(apperr.InvalidArgument, "尚未设置主密码")
+	}
+
+	// ChangeMasterPassword 还需要原主密码确认身份，因此这里自行读取而不使用 unlock
+	oldPassword := os.Getenv(passwordEnv)
+	if oldPassword == "" {
+		oldPassword, err = c.readPassword("当前主密码: ")
+		if err != nil {
+			return err
+		}
+	}
+	if err := c.app.Unlock(oldPassword); err != nil {
+		return err
+	}
+
+	newPassword := os.Getenv(newPasswordEnv)
+	if newPassword == "" {
+		newPassword, err = c.readPassword("新主密码: ")
+		if err != nil {
+			return err
+		}
+		confirm, err := c.readPassword("确认新主密码: ")
+		if err != nil {
+			return err
+		}
+		if confirm != newPassword {
+			return apperr.New(apperr.InvalidArgument, "两次输入的新主密码不一致")
+		}
+	}
+
+	if err := c.app.ChangeMasterPassword(oldPassword, newPassword); err != nil {
+		return err
+	}
+
+	if c.json {
+		return c.printJSON(map[string]bool{"changed": true})
+	}
+	fmt.Fprintln(c.stdout, "已修改主密码")
+	return nil
+}
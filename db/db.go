@@ -4,7 +4,8 @@ import (
 	"auth/model"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"path/filepath"
 	"strings"
 
 	_ "modernc.org/sqlite"
@@ -12,60 +13,143 @@ import (
 
 var DB *sql.DB
 
-func InitDB() {
+// FileName 数据目录下的数据库文件名
+const FileName = "data.db"
+
+// openPragmas 每个连接打开时执行的 PRAGMA
+// 图形界面和命令行可能同时写入，遇到锁时最多等待 5 秒而不是立即返回 database is locked；
+// WAL 模式下读取不会被写入阻塞
+const openPragmas = "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+
+// InitDB 打开数据目录下的数据库并执行升级
+func InitDB(dataDir string) error {
 	var err error
 	// 使用纯 Go SQLite 库
-	DB, err = sql.Open("sqlite", "file:data.db")
+	DB, err = sql.Open("sqlite", filepath.Join(dataDir, FileName)+openPragmas)
 	if err != nil {
-		log.Fatalf("连接数据库失败: %v", err)
+		return fmt.Errorf("连接数据库失败: %w", err)
+	}
+
+	// 执行数据库升级
+	if err = migrate(DB); err != nil {
+		return fmt.Errorf("初始化数据库失败: %w", err)
 	}
 
-	// 执行数据库初始化操作
-	_, err = DB.Exec(`CREATE TABLE IF NOT EXISTS secret (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		account_type INTEGER NOT NULL,
-		account_name TEXT NOT NULL,
-		server_name TEXT,
-		encrypted_secret TEXT NOT NULL
-	)`)
+	slog.Info("数据库初始化成功")
+	return nil
+
+}
+
+// SealFunc 在账户的行 ID 确定后加密账户，返回绑定到该行、可以直接写入的记录
+type SealFunc func(secret model.Secret) (model.Secret, error)
+
+// PendingSecret 等待写入的账户，密钥和名称在写入时由 Seal 加密
+type PendingSecret struct {
+	Secret model.Secret
+	Seal   SealFunc
+}
+
+// InsertSecret 插入一个账户，密文绑定到新分配的行 ID，返回该 ID
+func InsertSecret(pending PendingSecret) (uint, error) {
+	if DB == nil {
+		return 0, sql.ErrConnDone // 数据库未初始化
+	}
+
+	tx, err := DB.Begin()
 	if err != nil {
-		log.Fatalf("创建表失败: %v", err)
+		return 0, err
 	}
+	defer tx.Rollback()
 
-	log.Println("数据库初始化成功")
+	id, err := insertSecret(tx, pending)
+	if err != nil {
+		slog.Error("插入失败", "err", err)
+		return 0, err
+	}
 
+	slog.Debug("插入成功", "id", id)
+	return id, tx.Commit()
 }
 
-func InsertSecret(accountType int, accountName string, serverName string, encryptedSecret string) error {
+// ApplyImport 在同一个事务中插入新账户并按 ID 覆盖已有账户，任何一步失败都会整体回滚
+func ApplyImport(inserts []PendingSecret, updates []PendingSecret) error {
 	if DB == nil {
 		return sql.ErrConnDone // 数据库未初始化
 	}
 
-	_, err := DB.Exec(`INSERT INTO secret (account_type, account_name, server_name, encrypted_secret) VALUES (?,?,?, ?)`,
-		accountType, accountName, serverName, encryptedSecret)
+	tx, err := DB.Begin()
 	if err != nil {
-		log.Printf("插入失败: %v\n", err)
 		return err
 	}
+	defer tx.Rollback()
 
-	log.Println("插入成功")
-	return nil
+	for _, pending := range inserts {
+		if _, err := insertSecret(tx, pending); err != nil {
+			slog.Error("插入失败", "err", err)
+			return err
+		}
+	}
+
+	for _, pending := range updates {
+		secret, err := pending.Seal(pending.Secret)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE secret SET account_type = ?, account_name = ?, server_name = ?, encrypted_secret = ?,
+			counter = ?, algorithm = ?, digits = ?, period = ? WHERE id = ?`,
+			secret.AccountType, secret.AccountName, secret.ServerName, secret.EncryptedSecret,
+			secret.Counter, secret.Algorithm, secret.Digits, secret.Period, secret.ID)
+		if err != nil {
+			slog.Error("覆盖失败", "id", secret.ID, "err", err)
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// insertSecret 先以空的密文和名称插入取得行 ID，再写入绑定到该 ID 的密文，明文不会写入数据库
+func insertSecret(tx *sql.Tx, pending PendingSecret) (uint, error) {
+	secret := pending.Secret
+	result, err := tx.Exec(`INSERT INTO secret (account_type, account_name, server_name, encrypted_secret, counter, algorithm, digits, period)
+		VALUES (?,'','','',?,?,?,?)`,
+		secret.AccountType, secret.Counter, secret.Algorithm, secret.Digits, secret.Period)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	secret.ID = uint(id)
+
+	secret, err = pending.Seal(secret)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec("UPDATE secret SET account_name = ?, server_name = ?, encrypted_secret = ? WHERE id = ?",
+		secret.AccountName, secret.ServerName, secret.EncryptedSecret, id)
+	return secret.ID, err
 }
 
 func GetSecretsList() ([]model.Secret, error) {
 	if DB == nil {
 		return nil, sql.ErrConnDone // 数据库未初始化
 	}
-	rows, err := DB.Query("SELECT id,account_type,account_name, server_name, encrypted_secret FROM secret")
+	rows, err := DB.Query(`SELECT id, account_type, account_name, server_name, encrypted_secret,
+		counter, algorithm, digits, period FROM secret`)
 
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	var secrets []model.Secret
 	for rows.Next() {
 		var secret model.Secret
-		err := rows.Scan(&secret.ID, &secret.AccountType, &secret.AccountName, &secret.ServerName, &secret.EncryptedSecret)
+		err := rows.Scan(&secret.ID, &secret.AccountType, &secret.AccountName, &secret.ServerName, &secret.EncryptedSecret,
+			&secret.Counter, &secret.Algorithm, &secret.Digits, &secret.Period)
 		if err != nil {
 			return nil, err
 		}
@@ -79,6 +163,45 @@ func GetSecretsList() ([]model.Secret, error) {
 	return secrets, nil
 }
 
+// GetSecretsByIDs 按 ID 查询多条记录，结果按 ID 排序，不存在的 ID 会被忽略
+func GetSecretsByIDs(ids []int) ([]model.Secret, error) {
+	if DB == nil {
+		return nil, sql.ErrConnDone // 数据库未初始化
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`SELECT id, account_type, account_name, server_name, encrypted_secret,
+		counter, algorithm, digits, period FROM secret WHERE id IN (%s) ORDER BY id`, strings.Join(placeholders, ","))
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var secrets []model.Secret
+	for rows.Next() {
+		var secret model.Secret
+		err := rows.Scan(&secret.ID, &secret.AccountType, &secret.AccountName, &secret.ServerName, &secret.EncryptedSecret,
+			&secret.Counter, &secret.Algorithm, &secret.Digits, &secret.Period)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, rows.Err()
+}
+
+// DeleteSecret 删除多个账户，一个都没有删除时返回 sql.ErrNoRows
 func DeleteSecret(ids []int) error {
 	if DB == nil {
 		return sql.ErrConnDone // 数据库未初始化
@@ -96,24 +219,81 @@ func DeleteSecret(ids []int) error {
 	query := fmt.Sprintf("DELETE FROM secret WHERE id IN (%s)", strings.Join(placeholders, ","))
 
 	// 执行删除操作
-	_, err := DB.Exec(query, args...)
+	result, err := DB.Exec(query, args...)
 
 	if err != nil {
-		log.Printf("删除失败: %v\n", err)
+		slog.Error("删除失败", "err", err)
 		return err
 	}
 
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
 	return nil
 }
 
-func UpdateSecret(id int, accountName string, serverName string, accountType int) error {
-	_, err := DB.Exec("UPDATE secret SET account_name = ?, server_name = ?, account_type = ? WHERE id = ?", accountName, serverName, accountType, id)
+// UpdateSecret 修改账户信息，账户不存在时返回 sql.ErrNoRows
+// 名称和账户类型都参与加密，由 reseal 解密旧记录后按修改后的信息重新加密
+func UpdateSecret(id int, accountName string, serverName string, accountType int, reseal func(old, updated model.Secret) (model.Secret, error)) error {
+	if DB == nil {
+		return sql.ErrConnDone // 数据库未初始化
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
+	var old model.Secret
+	err = tx.QueryRow(`SELECT id, account_type, account_name, server_name, encrypted_secret,
+		counter, algorithm, digits, period FROM secret WHERE id = ?`, id).
+		Scan(&old.ID, &old.AccountType, &old.AccountName, &old.ServerName, &old.EncryptedSecret,
+			&old.Counter, &old.Algorithm, &old.Digits, &old.Period)
 	if err != nil {
-		log.Printf("编辑失败: %v\n", err)
 		return err
 	}
 
-	return nil
+	updated := old
+	updated.AccountName = accountName
+	updated.ServerName = serverName
+	updated.AccountType = uint(accountType)
+	sealed, err := reseal(old, updated)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE secret SET account_name = ?, server_name = ?, account_type = ?, encrypted_secret = ? WHERE id = ?",
+		sealed.AccountName, sealed.ServerName, accountType, sealed.EncryptedSecret, id)
+	if err != nil {
+		slog.Error("编辑失败", "id", id, "err", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// IncrementCounter 将 HOTP 账户的计数器加一，返回递增后的账户信息
+// 读取和递增在同一条语句中完成，保证并发调用时每个计数器只会被使用一次
+func IncrementCounter(id int) (model.Secret, error) {
+	if DB == nil {
+		return model.Secret{}, sql.ErrConnDone // 数据库未初始化
+	}
+
+	var secret model.Secret
+	err := DB.QueryRow(`UPDATE secret SET counter = counter + 1 WHERE id = ? AND account_type = ?
+		RETURNING id, account_type, account_name, server_name, encrypted_secret, counter, algorithm, digits, period`,
+		id, model.AccountTypeHOTP).Scan(&secret.ID, &secret.AccountType, &secret.AccountName, &secret.ServerName,
+		&secret.EncryptedSecret, &secret.Counter, &secret.Algorithm, &secret.Digits, &secret.Period)
+	if err != nil {
+		slog.Error("更新计数器失败", "id", id, "err", err)
+		return model.Secret{}, err
+	}
 
+	return secret, nil
 }
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// MigrateLegacyFile 数据目录中还没有数据库时，把工作目录或程序所在目录下的旧 data.db 复制过来
+// data.db 是很常见的文件名，只迁移含有 secret 表的数据库，原文件保持不变
+func MigrateLegacyFile(dataDir string) error {
+	target := filepath.Join(dataDir, FileName)
+	if _, err := os.Stat(target); err == nil {
+		return nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	for _, dir := range legacyDirs() {
+		source := filepath.Join(dir, FileName)
+		if sameFile(source, target) {
+			continue
+		}
+		if _, err := os.Stat(source); err != nil {
+			continue
+		}
+
+		copied, err := copyLegacyFile(source, target)
+		if err != nil {
+			return fmt.Errorf("迁移旧数据库 %s 失败: %w", source, err)
+		}
+		if copied {
+			slog.Info("已将旧数据库复制到数据目录，确认无误后可以删除原文件", "from", source, "to", target)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// legacyDirs 旧版本可能存放数据库的目录：工作目录和可执行文件所在目录
+func legacyDirs() []string {
+	var dirs []string
+	if wd, err := os.Getwd(); err == nil {
+		dirs = append(dirs, wd)
+	}
+	if execPath, err := os.Executable(); err == nil {
+		dirs = append(dirs, filepath.Dir(execPath))
+	}
+	return dirs
+}
+
+func sameFile(a string, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// copyLegacyFile 以只读方式打开 source，确认是本程序的数据库后复制到 target，不是时返回 false
+// 使用 VACUUM INTO 复制，WAL 中尚未写回的数据也会包含在内
+func copyLegacyFile(source string, target string) (bool, error) {
+	dsn := (&url.URL{Scheme: "file", Path: filepath.ToSlash(source), RawQuery: "mode=ro"}).String()
+	legacy, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return false, err
+	}
+	defer legacy.Close()
+
+	var tables int
+	err = legacy.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'secret'").Scan(&tables)
+	if err != nil || tables == 0 {
+		slog.Debug("忽略不是本程序数据库的文件", "path", source, "err", err)
+		return false, nil
+	}
+
+	if _, err := legacy.Exec("VACUUM INTO ?", target); err != nil {
+		os.Remove(target)
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+// migration 一个数据库升级步骤，执行成功后数据库的 user_version 变为 version
+type migration struct {
+	version     int
+	description string
+	up          func(tx *sql.Tx) error
+}
+
+// migrations 按版本顺序排列的升级步骤，只能在末尾追加，不能修改已发布的步骤
+//
+// 早期版本在启动时直接补字段而没有记录版本号，所以旧数据库的 user_version 为 0 但可能已经有部分字段，
+// 前几个步骤都需要允许重复执行
+var migrations = []migration{
+	{1, "创建 secret 表", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS secret (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			account_type INTEGER NOT NULL,
+			account_name TEXT NOT NULL,
+			server_name TEXT,
+			encrypted_secret TEXT NOT NULL
+		)`)
+		return err
+	}},
+	{2, "secret 表增加 HOTP 计数器", func(tx *sql.Tx) error {
+		return ensureColumn(tx, "secret", "counter", "INTEGER NOT NULL DEFAULT 0")
+	}},
+	{3, "secret 表增加算法、位数和周期", func(tx *sql.Tx) error {
+		if err := ensureColumn(tx, "secret", "algorithm", "TEXT NOT NULL DEFAULT 'SHA1'"); err != nil {
+			return err
+		}
+		if err := ensureColumn(tx, "secret", "digits", "INTEGER NOT NULL DEFAULT 6"); err != nil {
+			return err
+		}
+		return ensureColumn(tx, "secret", "period", "INTEGER NOT NULL DEFAULT 30")
+	}},
+	{4, "创建 vault 表", func(tx *sql.Tx) error {
+		// 保险库头信息：主密码校验值和密钥派生参数，只有一行
+		_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS vault (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			verifier TEXT NOT NULL
+		)`)
+		return err
+	}},
+	{5, "vault 表增加密钥派生参数", func(tx *sql.Tx) error {
+		columns := []struct{ name, definition string }{
+			{"kdf_version", "INTEGER NOT NULL DEFAULT 1"},
+			{"kdf_salt", "BLOB"},
+			{"kdf_time", "INTEGER NOT NULL DEFAULT 0"},
+			{"kdf_memory", "INTEGER NOT NULL DEFAULT 0"},
+			{"kdf_threads", "INTEGER NOT NULL DEFAULT 0"},
+		}
+		for _, column := range columns {
+			if err := ensureColumn(tx, "vault", column.name, column.definition); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{6, "vault 表增加保险库 ID", func(tx *sql.Tx) error {
+		// 为空表示密文还没有绑定到所在的行，需要主密码才能重新加密，在下次解锁时完成
+		return ensureColumn(tx, "vault", "vault_id", "TEXT NOT NULL DEFAULT ''")
+	}},
+	{7, "vault 表增加账户信息加密标记", func(tx *sql.Tx) error {
+		// 已有的账户名称和服务商仍为明文，同样在下次解锁时加密
+		return ensureColumn(tx, "vault", "encrypted_metadata", "INTEGER NOT NULL DEFAULT 0")
+	}},
+	{8, "创建 quarantine 表", func(tx *sql.Tx) error {
+		// 升级保险库时无法用当前主密码解密的账户，保留原来的行 ID 和密文，不出现在账户列表和备份中
+		_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS quarantine (
+			id INTEGER PRIMARY KEY,
+			account_type INTEGER NOT NULL,
+			account_name TEXT NOT NULL,
+			server_name TEXT,
+			encrypted_secret TEXT NOT NULL,
+			counter INTEGER NOT NULL DEFAULT 0,
+			algorithm TEXT NOT NULL DEFAULT 'SHA1',
+			digits INTEGER NOT NULL DEFAULT 6,
+			period INTEGER NOT NULL DEFAULT 30
+		)`)
+		return err
+	}},
+}
+
+// SchemaVersion 当前程序支持的数据库版本
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// migrate 依次执行尚未执行的升级步骤，每个步骤在单独的事务中完成
+func migrate(db *sql.DB) error {
+	var current int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&current); err != nil {
+		return fmt.Errorf("读取数据库版本失败: %w", err)
+	}
+
+	if current > SchemaVersion() {
+		return fmt.Errorf("数据库版本 %d 高于程序支持的版本 %d，请升级程序后再打开", current, SchemaVersion())
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		if err := applyMigration(db, m); err != nil {
+			return fmt.Errorf("执行数据库升级 %d（%s）失败: %w", m.version, m.description, err)
+		}
+		slog.Info("数据库已升级", "version", m.version, "description", m.description)
+	}
+
+	return nil
+}
+
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+
+	// PRAGMA 不支持参数占位符
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", m.version)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ensureColumn 检查表中是否存在指定字段，不存在时追加
+func ensureColumn(tx *sql.Tx, table string, column string, definition string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"auth/model"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// execer 是 *sql.DB 和 *sql.Tx 共有的执行方法
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// GetVaultHeader 获取保险库头信息，尚未设置主密码时第二个返回值为 false
+func GetVaultHeader() (model.VaultHeader, bool, error) {
+	if DB == nil {
+		return model.VaultHeader{}, false, sql.ErrConnDone // 数据库未初始化
+	}
+
+	var header model.VaultHeader
+	err := DB.QueryRow(`SELECT verifier, kdf_version, kdf_salt, kdf_time, kdf_memory, kdf_threads, vault_id, encrypted_metadata FROM vault WHERE id = 1`).
+		Scan(&header.Verifier, &header.KDF.Version, &header.KDF.Salt, &header.KDF.Time, &header.KDF.Memory, &header.KDF.Threads, &header.VaultID, &header.EncryptedMetadata)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.VaultHeader{}, false, nil
+	}
+	if err != nil {
+		return model.VaultHeader{}, false, err
+	}
+
+	return header, true, nil
+}
+
+// saveVaultHeader 保存保险库头信息
+func saveVaultHeader(db execer, header model.VaultHeader) error {
+	_, err := db.Exec(`INSERT OR REPLACE INTO vault (id, verifier, kdf_version, kdf_salt, kdf_time, kdf_memory, kdf_threads, vault_id, encrypted_metadata)
+		VALUES (1, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		header.Verifier, header.KDF.Version, header.KDF.Salt, header.KDF.Time, header.KDF.Memory, header.KDF.Threads, header.VaultID, header.EncryptedMetadata)
+	return err
+}
+
+// RewrapVault 在同一个事务中用 reencrypt 重新加密所有账户并保存新的头信息，任何一步失败都会整体回滚
+// reencrypt 收到的 secret 只有 ID、AccountType、名称和 EncryptedSecret，返回重新加密后的记录
+// reencrypt 的第二个返回值为 true 时，该账户连同返回的名称移入 quarantine 表，密文保持不变；
+// 之前已在 quarantine 表中的账户同样交给 reencrypt，只更新名称。返回本次移入 quarantine 表的账户 ID
+func RewrapVault(header model.VaultHeader, reencrypt func(secret model.Secret) (model.Secret, bool, error)) ([]uint, error) {
+	if DB == nil {
+		return nil, sql.ErrConnDone // 数据库未初始化
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// 先读取已有的 quarantine 记录，本次移入的记录名称已经用新密钥加密
+	quarantined, err := queryRewrapRows(tx, "quarantine")
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := queryRewrapRows(tx, "secret")
+	if err != nil {
+		return nil, err
+	}
+
+	var moved []uint
+	for _, secret := range secrets {
+		sealed, quarantine, err := reencrypt(secret)
+		if err != nil {
+			return nil, err
+		}
+		if quarantine {
+			err = moveToQuarantine(tx, sealed)
+			moved = append(moved, secret.ID)
+		} else {
+			_, err = tx.Exec("UPDATE secret SET account_name = ?, server_name = ?, encrypted_secret = ? WHERE id = ?",
+				sealed.AccountName, sealed.ServerName, sealed.EncryptedSecret, secret.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, secret := range quarantined {
+		sealed, _, err := reencrypt(secret)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec("UPDATE quarantine SET account_name = ?, server_name = ? WHERE id = ?",
+			sealed.AccountName, sealed.ServerName, secret.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := saveVaultHeader(tx, header); err != nil {
+		return nil, err
+	}
+
+	return moved, tx.Commit()
+}
+
+// queryRewrapRows 读取 table 中重新加密需要的字段
+func queryRewrapRows(tx *sql.Tx, table string) ([]model.Secret, error) {
+	rows, err := tx.Query(fmt.Sprintf("SELECT id, account_type, account_name, server_name, encrypted_secret FROM %s", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var secrets []model.Secret
+	for rows.Next() {
+		var secret model.Secret
+		if err := rows.Scan(&secret.ID, &secret.AccountType, &secret.AccountName, &secret.ServerName, &secret.EncryptedSecret); err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, rows.Err()
+}
+
+// moveToQuarantine 把账户移入 quarantine 表，名称使用 secret 中的值，其余字段原样复制
+func moveToQuarantine(tx *sql.Tx, secret model.Secret) error {
+	_, err := tx.Exec(`INSERT INTO quarantine (id, account_type, account_name, server_name, encrypted_secret, counter, algorithm, digits, period)
+		SELECT id, account_type, ?, ?, encrypted_secret, counter, algorithm, digits, period FROM secret WHERE id = ?`,
+		secret.AccountName, secret.ServerName, secret.ID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM secret WHERE id = ?", secret.ID)
+	return err
+}
+
+// GetQuarantinedSecrets 返回 quarantine 表中的账户，按 ID 排序
+func GetQuarantinedSecrets() ([]model.Secret, error) {
+	if DB == nil {
+		return nil, sql.ErrConnDone // 数据库未初始化
+	}
+
+	rows, err := DB.Query(`SELECT id, account_type, account_name, server_name, encrypted_secret,
+		counter, algorithm, digits, period FROM quarantine ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var secrets []model.Secret
+	for rows.Next() {
+		var secret model.Secret
+		err := rows.Scan(&secret.ID, &secret.AccountType, &secret.AccountName, &secret.ServerName, &secret.EncryptedSecret,
+			&secret.Counter, &secret.Algorithm, &secret.Digits, &secret.Period)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, rows.Err()
+}
+
+// DeleteQuarantined 删除 quarantine 表中的所有账户
+func DeleteQuarantined() error {
+	if DB == nil {
+		return sql.ErrConnDone // 数据库未初始化
+	}
+
+	_, err := DB.Exec("DELETE FROM quarantine")
+	return err
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"auth/apperr"
+	"auth/db"
+	"auth/logger"
+	"auth/model"
+	"auth/utils"
+	gotp "auth/utils/otp_extractor"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+)
+
+// GetAccountQRCode 校验主密码后把账户还原为 otpauth:// 链接，返回其 PNG 二维码的 data URL
+// 用于把账户转移到手机上的其他验证器，二维码中包含明文密钥，因此需要再次输入主密码
+func (a *App) GetAccountQRCode(id int, password string) (string, error) {
+	logger.Protect(password)
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.cipher == nil {
+		return "", ErrVaultLocked
+	}
+
+	if err := checkMasterPassword(password); err != nil {
+		return "", err
+	}
+
+	secrets, err := db.GetSecretsByIDs([]int{id})
+	if err != nil {
+		return "", apperr.Wrap(apperr.DBFailure, "读取账户失败", err)
+	}
+	if len(secrets) == 0 {
+		return "", apperr.New(apperr.NotFound, "账户不存在")
+	}
+	secret, plaintext, err := a.cipher.OpenAccount(secrets[0])
+	if err != nil {
+		return "", apperr.Wrap(apperr.Internal, fmt.Sprintf("解密账户 %d 失败", id), err)
+	}
+
+	uri, err := buildOtpauthURI(secret, plaintext)
+	if err != nil {
+		return "", err
+	}
+
+	img, err := utils.EncodeQRCodePNG(uri)
+	if err != nil {
+		return "", apperr.Wrap(apperr.Internal, "生成二维码失败", err)
+	}
+
+	slog.Info("导出账户二维码", "id", id)
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(img), nil
+}
+
+// ExportMigrationQRCodes 校验主密码后把选中的账户导出为谷歌验证器可扫描的迁移二维码，返回 PNG 图片的 data URL
+// 与 GetAccountQRCode 相同，二维码中包含明文密钥，因此需要再次输入主密码
+func (a *App) ExportMigrationQRCodes(ids []int, password string) ([]string, error) {
+	logger.Protect(password)
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.cipher == nil {
+		return nil, ErrVaultLocked
+	}
+	c := a.cipher
+
+	if err := checkMasterPassword(password); err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return nil, apperr.New(apperr.InvalidArgument, "请选择要导出的账户")
+	}
+
+	// 去重后与查询结果比较，任何一个 ID 不存在都不导出，避免少导出账户而不自知
+	unique := make([]int, 0, len(ids))
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	secrets, err := db.GetSecretsByIDs(unique)
+	if err != nil {
+		return nil, apperr.Wrap(apperr.DBFailure, "读取账户失败", err)
+	}
+	if len(secrets) != len(unique) {
+		return nil, apperr.New(apperr.NotFound, "账户不存在")
+	}
+
+	entries := make([]gotp.OtpEntry, 0, len(secrets))
+	for _, secret := range secrets {
+		secret, plaintext, err := c.OpenAccount(secret)
+		if err != nil {
+			return nil, apperr.Wrap(apperr.Internal, fmt.Sprintf("解密账户 %d 失败", secret.ID), err)
+		}
+
+		entry := gotp.OtpEntry{
+			Name:      secret.AccountName,
+			Secret:    plaintext,
+			Issuer:    secret.ServerName,
+			Algorithm: secret.Algorithm,
+			Digits:    int(secret.Digits),
+			Period:    int(secret.Period),
+		}
+		switch secret.AccountType {
+		case model.AccountTypeTOTP:
+			entry.Type = "totp"
+		case model.AccountTypeHOTP:
+			entry.Type = "hotp"
+			entry.Counter = int64(secret.Counter)
+		default:
+			return nil, apperr.New(apperr.Unsupported, "账户 "+secret.AccountName+" 无法导出: 谷歌验证器不支持 Steam 令牌")
+		}
+		entries = append(entries, entry)
+	}
+
+	urls, err := gotp.BuildMigrationURLs(entries)
+	if err != nil {
+		return nil, apperr.Wrap(apperr.Unsupported, "生成迁移链接失败", err)
+	}
+
+	images := make([]string, 0, len(urls))
+	for _, u := range urls {
+		img, err := utils.EncodeQRCodePNG(u)
+		if err != nil {
+			return nil, apperr.Wrap(apperr.Internal, "生成二维码失败", err)
+		}
+		images = append(images, "data:image/png;base64,"+base64.StdEncoding.EncodeToString(img))
+	}
+
+	return images, nil
+}
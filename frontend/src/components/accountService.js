@@ -1,4 +1,4 @@
-import { GetSecretsList, InsertSecret, DeleteSecret, RecognizeQRCode, UpdateSecret } from '../../wailsjs/go/main/App';
+import { GetSecretsList, InsertSecret, DeleteSecret, PreviewImport, CommitImport, UpdateSecret, ImportURI, GetAccountQRCode, ExportMigrationQRCodes, NextHOTPCode, GetQuarantinedAccounts, DeleteQuarantinedAccounts } from '../../wailsjs/go/main/App';
 
 // 获取账户列表
 export const getSecretsList = async () => {
@@ -22,17 +22,66 @@ export const addManualSecret = async (accountName, serverName, secret, accountTy
   }
 };
 
-// 通过二维码添加账户
-export const addQRCodeSecret = async (imageData) => {
+// 识别二维码中的账户，返回 {token, entries}，此时还没有写入
+export const previewImport = async (imageData) => {
   try {
-    await RecognizeQRCode(imageData);
-    return true;
+    return await PreviewImport(imageData);
   } catch (error) {
     console.error('二维码识别失败:', error);
     throw error;
   }
 };
 
+// 导入预览中选中的账户，返回新增、跳过和覆盖的账户数量
+export const commitImport = async (token, indexes, mode) => {
+  try {
+    return await CommitImport(token, indexes, mode);
+  } catch (error) {
+    console.error('导入账户失败:', error);
+    throw error;
+  }
+};
+
+// 通过粘贴的 otpauth 链接添加账户，返回总计和每一行的导入结果
+export const importURI = async (text, mode) => {
+  try {
+    return await ImportURI(text, mode);
+  } catch (error) {
+    console.error('导入链接失败:', error);
+    throw error;
+  }
+};
+
+// 校验主密码后获取账户的 otpauth 二维码，返回 PNG 图片的 data URL
+export const getAccountQRCode = async (id, password) => {
+  try {
+    return await GetAccountQRCode(id, password);
+  } catch (error) {
+    console.error('生成账户二维码失败:', error);
+    throw error;
+  }
+};
+
+// 校验主密码后把选中的账户编码为谷歌验证器迁移二维码，返回 PNG 图片的 data URL 列表
+export const exportMigrationQRCodes = async (ids, password) => {
+  try {
+    return await ExportMigrationQRCodes(ids, password);
+  } catch (error) {
+    console.error('生成迁移二维码失败:', error);
+    throw error;
+  }
+};
+
+// 推进 HOTP 账户的计数器，返回新的验证码
+export const nextHOTPCode = async (id) => {
+  try {
+    return await NextHOTPCode(id);
+  } catch (error) {
+    console.error('生成 HOTP 验证码失败:', error);
+    throw error;
+  }
+};
+
 // 更新账户
 export const updateSecret = async (id, accountName, serverName, accountType) => {
   try {
@@ -55,8 +104,29 @@ export const deleteSecret = async (id) => {
   }
 };
 
-// 处理账户列表数据，添加 timeLeft 字段
-export const processAccountList = (list, currentTimeLeft) => {
+// 获取升级保险库时无法解密、已移出账户列表的账户
+export const getQuarantinedAccounts = async () => {
+  try {
+    return await GetQuarantinedAccounts();
+  } catch (error) {
+    console.error('获取无法解密的账户失败:', error);
+    throw error;
+  }
+};
+
+// 删除所有无法解密的账户
+export const deleteQuarantinedAccounts = async () => {
+  try {
+    await DeleteQuarantinedAccounts();
+    return true;
+  } catch (error) {
+    console.error('删除无法解密的账户失败:', error);
+    throw error;
+  }
+};
+
+// 处理账户列表数据，添加 timeLeft 字段，取后端按各账户周期计算的剩余秒数
+export const processAccountList = (list) => {
   if (!list || list.length === 0) {
     console.log('获取到的账户列表为空');
     return [];
@@ -64,6 +134,26 @@ export const processAccountList = (list, currentTimeLeft) => {
   
   return list.map(account => ({
     ...account, 
-    timeLeft: currentTimeLeft
+    timeLeft: account.Remaining
   }));
-};
\ No newline at end of file
+};
+// 后端返回的错误为 {code, message} 对象，取出可显示的错误信息
+export const errorMessage = (error) => {
+  if (error && typeof error === 'object' && error.message) {
+    return error.message;
+  }
+  return String(error);
+};
+
+// 把导入统计转换为提示文字
+export const describeImportSummary = (summary) => {
+  const parts = [`新增 ${summary.added} 个账户`];
+  if (summary.skipped) parts.push(`跳过 ${summary.skipped} 个重复账户`);
+  if (summary.updated) parts.push(`覆盖 ${summary.updated} 个已有账户`);
+  return [parts.join('，'), ...(summary.warnings || [])].join('\n');
+};
+
+// 判断错误是否由保险库已锁定引起
+export const isVaultLocked = (error) => {
+  return !!error && error.code === 'VaultLocked';
+};
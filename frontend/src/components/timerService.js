@@ -1,38 +1,57 @@
-// 计算当前时间在 30 秒周期内的剩余秒数
-export const calculateTimeLeft = () => {
-  const nowSeconds = Math.floor(Date.now() / 1000);
-  return 30 - (nowSeconds % 30);
+// 服务器时间与本地时间的差值（秒），由 GetSecretsList 返回的 ServerTime 校正
+let clockOffset = 0;
+
+// 根据后端生成验证码时使用的时间校正本地时钟
+export const syncServerTime = (serverTime) => {
+  if (serverTime) {
+    clockOffset = serverTime - Math.floor(Date.now() / 1000);
+  }
+};
+
+// 计算账户当前验证码的剩余秒数，HOTP 账户（Period 为 0）返回 0
+export const calculateTimeLeft = (account) => {
+  const period = account.Period;
+  if (!period) return 0;
+  const nowSeconds = Math.floor(Date.now() / 1000) + clockOffset;
+  return period - (nowSeconds % period);
 };
 
 // 计算进度条偏移量
-export const calculateProgressOffset = (timeLeft) => {
+export const calculateProgressOffset = (timeLeft, period) => {
   const radius = 15;
   const circumference = 2 * Math.PI * radius;
-  const percentage = timeLeft / 30;
+  const percentage = period ? timeLeft / period : 1;
   return circumference * (1 - percentage);
 };
 
-// 检测是否需要刷新验证码
-export const needsCodeRefresh = (accounts, newTimeLeft) => {
-  if (accounts.length > 0) {
-    return newTimeLeft === 30 && accounts[0].timeLeft === 1;
-  }
-  return false;
+// 更新所有账户的 timeLeft，周期结束的账户先切换为后端提前生成的下一个验证码，
+// 随后由后端推送的 codes:refresh 事件补齐新的 NextCode
+export const tickAccounts = (accounts) => {
+  return accounts.map(acc => {
+    const timeLeft = calculateTimeLeft(acc);
+    if (acc.Period && timeLeft > acc.timeLeft) {
+      return { ...acc, timeLeft, Code: acc.NextCode || acc.Code, NextCode: '' };
+    }
+    return { ...acc, timeLeft };
+  });
 };
 
-// 更新账户列表中的所有 timeLeft 值
-export const updateAccountsTimeLeft = (accounts, newTimeLeft) => {
-  return accounts.map(acc => ({
-    ...acc,
-    timeLeft: newTimeLeft
-  }));
+// 把后端推送的验证码更新合并到账户列表
+export const applyCodeUpdates = (accounts, updates) => {
+  const byId = new Map(updates.map(update => [update.ID, update]));
+  if (updates.length > 0) syncServerTime(updates[0].ServerTime);
+  return accounts.map(acc => {
+    const update = byId.get(acc.ID);
+    if (!update) return acc;
+    return { ...acc, Code: update.Code, NextCode: update.NextCode, timeLeft: update.Remaining };
+  });
 };
 
 // 计算圆形进度条的样式
-export const getProgressStyle = (timeLeft) => {
+export const getProgressStyle = (timeLeft, period = 30) => {
   // 计算百分比，确保不低于 0
-  const percentage = Math.max(0, timeLeft / 30) * 100;
+  const percentage = Math.max(0, timeLeft / period) * 100;
   return {
     background: `radial-gradient(white 60%, transparent 61%), conic-gradient(#4285F4 ${percentage}%, #e0e0e0 ${percentage}%)`
   };
-};
\ No newline at end of file
+};
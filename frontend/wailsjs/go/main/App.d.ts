@@ -1,13 +1,48 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {main} from '../models';
 import {model} from '../models';
 
+export function ChangeMasterPassword(arg1:string,arg2:string):Promise<void>;
+
+export function CommitImport(arg1:string,arg2:Array<number>,arg3:string):Promise<main.ImportSummary>;
+
+export function DeleteQuarantinedAccounts():Promise<void>;
+
 export function DeleteSecret(arg1:Array<number>):Promise<void>;
 
+export function ExportBackup(arg1:string,arg2:string):Promise<void>;
+
+export function ExportMigrationQRCodes(arg1:Array<number>,arg2:string):Promise<Array<string>>;
+
+export function GetAccountQRCode(arg1:number,arg2:string):Promise<string>;
+
+export function GetQuarantinedAccounts():Promise<Array<main.QuarantinedAccount>>;
+
 export function GetSecretsList():Promise<Array<model.Secret>>;
 
+export function ImportAegis(arg1:Array<number>,arg2:string,arg3:string):Promise<main.ImportSummary>;
+
+export function ImportURI(arg1:string,arg2:string):Promise<main.URIImportReport>;
+
 export function InsertSecret(arg1:string,arg2:string,arg3:string,arg4:number):Promise<void>;
 
-export function RecognizeQRCode(arg1:Array<number>):Promise<void>;
+export function IsLocked():Promise<boolean>;
+
+export function IsVaultInitialized():Promise<boolean>;
+
+export function Lock():Promise<void>;
+
+export function NextHOTPCode(arg1:number):Promise<string>;
+
+export function PreviewImport(arg1:Array<number>):Promise<main.ImportPreview>;
+
+export function RecognizeQRCode(arg1:Array<number>,arg2:string):Promise<main.ImportSummary>;
+
+export function RestoreBackup(arg1:string,arg2:string,arg3:string):Promise<main.ImportSummary>;
+
+export function SetWindowVisible(arg1:boolean):Promise<void>;
+
+export function Unlock(arg1:string):Promise<void>;
 
 export function UpdateSecret(arg1:number,arg2:string,arg3:string,arg4:number):Promise<void>;
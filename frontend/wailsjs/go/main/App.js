@@ -2,20 +2,88 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function ChangeMasterPassword(arg1, arg2) {
+  return window['go']['main']['App']['ChangeMasterPassword'](arg1, arg2);
+}
+
+export function CommitImport(arg1, arg2, arg3) {
+  return window['go']['main']['App']['CommitImport'](arg1, arg2, arg3);
+}
+
+export function DeleteQuarantinedAccounts() {
+  return window['go']['main']['App']['DeleteQuarantinedAccounts']();
+}
+
 export function DeleteSecret(arg1) {
   return window['go']['main']['App']['DeleteSecret'](arg1);
 }
 
+export function ExportBackup(arg1, arg2) {
+  return window['go']['main']['App']['ExportBackup'](arg1, arg2);
+}
+
+export function ExportMigrationQRCodes(arg1, arg2) {
+  return window['go']['main']['App']['ExportMigrationQRCodes'](arg1, arg2);
+}
+
+export function GetAccountQRCode(arg1, arg2) {
+  return window['go']['main']['App']['GetAccountQRCode'](arg1, arg2);
+}
+
+export function GetQuarantinedAccounts() {
+  return window['go']['main']['App']['GetQuarantinedAccounts']();
+}
+
 export function GetSecretsList() {
   return window['go']['main']['App']['GetSecretsList']();
 }
 
+export function ImportAegis(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ImportAegis'](arg1, arg2, arg3);
+}
+
+export function ImportURI(arg1, arg2) {
+  return window['go']['main']['App']['ImportURI'](arg1, arg2);
+}
+
 export function InsertSecret(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['InsertSecret'](arg1, arg2, arg3, arg4);
 }
 
-export function RecognizeQRCode(arg1) {
-  return window['go']['main']['App']['RecognizeQRCode'](arg1);
+export function IsLocked() {
+  return window['go']['main']['App']['IsLocked']();
+}
+
+export function IsVaultInitialized() {
+  return window['go']['main']['App']['IsVaultInitialized']();
+}
+
+export function Lock() {
+  return window['go']['main']['App']['Lock']();
+}
+
+export function NextHOTPCode(arg1) {
+  return window['go']['main']['App']['NextHOTPCode'](arg1);
+}
+
+export function PreviewImport(arg1) {
+  return window['go']['main']['App']['PreviewImport'](arg1);
+}
+
+export function RecognizeQRCode(arg1, arg2) {
+  return window['go']['main']['App']['RecognizeQRCode'](arg1, arg2);
+}
+
+export function RestoreBackup(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RestoreBackup'](arg1, arg2, arg3);
+}
+
+export function SetWindowVisible(arg1) {
+  return window['go']['main']['App']['SetWindowVisible'](arg1);
+}
+
+export function Unlock(arg1) {
+  return window['go']['main']['App']['Unlock'](arg1);
 }
 
 export function UpdateSecret(arg1, arg2, arg3, arg4) {
@@ -1,10 +1,179 @@
+export namespace main {
+	
+	export class ImportPreviewEntry {
+	    index: number;
+	    name: string;
+	    issuer: string;
+	    type: string;
+	    algorithm: string;
+	    digits: number;
+	    period: number;
+	    counter: number;
+	    duplicate: boolean;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportPreviewEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.index = source["index"];
+	        this.name = source["name"];
+	        this.issuer = source["issuer"];
+	        this.type = source["type"];
+	        this.algorithm = source["algorithm"];
+	        this.digits = source["digits"];
+	        this.period = source["period"];
+	        this.counter = source["counter"];
+	        this.duplicate = source["duplicate"];
+	        this.error = source["error"];
+	    }
+	}
+	export class ImportPreview {
+	    token: string;
+	    entries: ImportPreviewEntry[];
+	    warnings?: string[];
+	    strategy: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportPreview(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.token = source["token"];
+	        this.entries = this.convertValues(source["entries"], ImportPreviewEntry);
+	        this.warnings = source["warnings"];
+	        this.strategy = source["strategy"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ImportSummary {
+	    added: number;
+	    skipped: number;
+	    updated: number;
+	    warnings?: string[];
+	    strategy?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.added = source["added"];
+	        this.skipped = source["skipped"];
+	        this.updated = source["updated"];
+	        this.warnings = source["warnings"];
+	        this.strategy = source["strategy"];
+	    }
+	}
+	export class QuarantinedAccount {
+	    id: number;
+	    name: string;
+	    issuer: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new QuarantinedAccount(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.issuer = source["issuer"];
+	    }
+	}
+	export class URIImportResult {
+	    line: number;
+	    added: string[];
+	    skipped: string[];
+	    updated: string[];
+	    code?: string;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new URIImportResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.line = source["line"];
+	        this.added = source["added"];
+	        this.skipped = source["skipped"];
+	        this.updated = source["updated"];
+	        this.code = source["code"];
+	        this.error = source["error"];
+	    }
+	}
+	export class URIImportReport {
+	    summary: ImportSummary;
+	    lines: URIImportResult[];
+	
+	    static createFrom(source: any = {}) {
+	        return new URIImportReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.summary = this.convertValues(source["summary"], ImportSummary);
+	        this.lines = this.convertValues(source["lines"], URIImportResult);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
 export namespace model {
 	
 	export class Secret {
 	    ID: number;
 	    AccountName: string;
 	    ServerName: string;
+	    AccountType: number;
+	    Algorithm: string;
+	    Digits: number;
+	    Period: number;
+	    Counter: number;
 	    Code: string;
+	    NextCode: string;
+	    Remaining: number;
+	    ServerTime: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new Secret(source);
@@ -15,7 +184,15 @@ export namespace model {
 	        this.ID = source["ID"];
 	        this.AccountName = source["AccountName"];
 	        this.ServerName = source["ServerName"];
+	        this.AccountType = source["AccountType"];
+	        this.Algorithm = source["Algorithm"];
+	        this.Digits = source["Digits"];
+	        this.Period = source["Period"];
+	        this.Counter = source["Counter"];
 	        this.Code = source["Code"];
+	        this.NextCode = source["NextCode"];
+	        this.Remaining = source["Remaining"];
+	        this.ServerTime = source["ServerTime"];
 	    }
 	}
 
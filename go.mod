@@ -3,10 +3,12 @@ module auth
 go 1.23.0
 
 require (
-	github.com/joho/godotenv v1.5.1
 	github.com/makiuchi-d/gozxing v0.1.1
 	github.com/pquerna/otp v1.4.0
 	github.com/wailsapp/wails/v2 v2.10.1
+	golang.org/x/crypto v0.33.0
+	golang.org/x/image v0.25.0
+	golang.org/x/term v0.30.0
 	google.golang.org/protobuf v1.33.0
 	modernc.org/sqlite v1.37.0
 )
@@ -38,11 +40,10 @@ require (
 	github.com/valyala/fasttemplate v1.2.2 // indirect
 	github.com/wailsapp/go-webview2 v1.0.19 // indirect
 	github.com/wailsapp/mimetype v1.4.1 // indirect
-	golang.org/x/crypto v0.33.0 // indirect
 	golang.org/x/exp v0.0.0-20250305212735-054e65f0b394 // indirect
 	golang.org/x/net v0.35.0 // indirect
 	golang.org/x/sys v0.31.0 // indirect
-	golang.org/x/text v0.22.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 	modernc.org/libc v1.62.1 // indirect
 	modernc.org/mathutil v1.7.1 // indirect
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"auth/apperr"
+	"auth/db"
+	"auth/logger"
+	"auth/model"
+	"auth/utils"
+	"auth/utils/aegis"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// 导入时遇到已存在账户的处理方式
+const (
+	DuplicateSkip      = "skip"      // 跳过，保留已有账户
+	DuplicateOverwrite = "overwrite" // 用导入的账户覆盖已有账户
+	DuplicateKeepBoth  = "keep"      // 两者都保留
+)
+
+// ImportSummary 一次导入的结果统计
+type ImportSummary struct {
+	Added   int `json:"added"`
+	Skipped int `json:"skipped"`
+	Updated int `json:"updated"`
+
+	Warnings []string `json:"warnings,omitempty"` // 不影响导入的问题，例如导出码缺页
+	Strategy string   `json:"strategy,omitempty"` // 识别二维码时使用的图像预处理方式，仅二维码导入时有值
+}
+
+// 单个账户的导入结果
+const (
+	importAdded   = "added"
+	importSkipped = "skipped"
+	importUpdated = "updated"
+)
+
+// knownAccount 数据库中已有的或本次导入中已加入的账户，用于查重
+type knownAccount struct {
+	secret    model.Secret // ID 为 0 表示本次导入新增的账户
+	plaintext string
+	insert    int // 本次新增的账户在 inserts 中的下标
+}
+
+// importSession 一次导入过程，所有改动在结束时由 db.ApplyImport 在同一个事务中写入
+type importSession struct {
+	cipher  *utils.Cipher
+	mode    string
+	known   []knownAccount
+	inserts []db.PendingSecret
+	updates map[uint]db.PendingSecret
+	summary ImportSummary
+}
+
+// runImport 在持有读锁的情况下执行一次导入，fn 返回错误时不写入任何账户
+func (a *App) runImport(mode string, fn func(s *importSession) error) (ImportSummary, error) {
+	switch mode {
+	case "":
+		mode = DuplicateSkip
+	case DuplicateSkip, DuplicateOverwrite, DuplicateKeepBoth:
+	default:
+		return ImportSummary{}, apperr.New(apperr.InvalidArgument, "未知的重复账户处理方式: "+mode)
+	}
+
+	// 导入期间持有读锁，避免与修改主密码交错
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.cipher == nil {
+		return ImportSummary{}, ErrVaultLocked
+	}
+
+	s, err := newImportSession(a.cipher, mode)
+	if err != nil {
+		return ImportSummary{}, err
+	}
+
+	if err := fn(s); err != nil {
+		return ImportSummary{}, err
+	}
+
+	if err := s.commit(); err != nil {
+		return ImportSummary{}, err
+	}
+	a.invalidateCodes()
+	return s.summary, nil
+}
+
+// newImportSession 读取并解密已有账户用于查重，调用方需持有 App 的读锁
+func newImportSession(c *utils.Cipher, mode string) (*importSession, error) {
+	secrets, err := db.GetSecretsList()
+	if err != nil {
+		return nil, apperr.Wrap(apperr.DBFailure, "读取账户失败", err)
+	}
+
+	s := &importSession{
+		cipher:  c,
+		mode:    mode,
+		known:   make([]knownAccount, 0, len(secrets)),
+		updates: make(map[uint]db.PendingSecret),
+	}
+	for _, secret := range secrets {
+		secret, plaintext, err := c.OpenAccount(secret)
+		if err != nil {
+			slog.Warn("解密失败，不参与查重", "id", secret.ID, "err", err)
+			continue
+		}
+		s.known = append(s.known, knownAccount{secret: secret, plaintext: plaintext, insert: -1})
+	}
+
+	return s, nil
+}
+
+// commit 在同一个事务中写入本次导入新增和覆盖的账户
+func (s *importSession) commit() error {
+	updates := make([]db.PendingSecret, 0, len(s.updates))
+	for _, pending := range s.updates {
+		updates = append(updates, pending)
+	}
+	if err := db.ApplyImport(s.inserts, updates); err != nil {
+		return apperr.Wrap(apperr.DBFailure, "写入导入的账户失败", err)
+	}
+	return nil
+}
+
+// add 校验一个待导入的账户，并按重复账户处理方式决定新增、跳过或覆盖，返回处理结果
+func (s *importSession) add(secret model.Secret, plaintext string) (string, error) {
+	secret, plaintext, err := normalizeSecret(secret, plaintext)
+	if err != nil {
+		return "", err
+	}
+
+	dup := s.findDuplicate(secret, plaintext)
+	if dup >= 0 && s.mode == DuplicateSkip {
+		s.summary.Skipped++
+		return importSkipped, nil
+	}
+
+	// 密文绑定到所在的行，新增账户的 ID 在写入时才确定，由 ApplyImport 调用 Seal 加密
+	if dup < 0 || s.mode == DuplicateKeepBoth {
+		s.known = append(s.known, knownAccount{secret: secret, plaintext: plaintext, insert: len(s.inserts)})
+		s.inserts = append(s.inserts, db.PendingSecret{Secret: secret, Seal: sealWith(s.cipher, plaintext)})
+		s.summary.Added++
+		return importAdded, nil
+	}
+
+	// 覆盖：已有账户按 ID 更新，本次新增的账户直接替换待写入的记录
+	known := &s.known[dup]
+	secret.ID = known.secret.ID
+	pending := db.PendingSecret{Secret: secret, Seal: sealWith(s.cipher, plaintext)}
+	if known.insert >= 0 {
+		s.inserts[known.insert] = pending
+	} else {
+		s.updates[secret.ID] = pending
+	}
+	known.secret = secret
+	known.plaintext = plaintext
+	s.summary.Updated++
+	return importUpdated, nil
+}
+
+// findDuplicate 查找与待导入账户重复的账户下标，不存在时返回 -1
+// 密钥相同，或服务商和账户名称都相同（不区分大小写）即视为重复
+func (s *importSession) findDuplicate(secret model.Secret, plaintext string) int {
+	for i, known := range s.known {
+		if known.secret.AccountType == secret.AccountType && known.plaintext == plaintext {
+			return i
+		}
+		if strings.EqualFold(known.secret.ServerName, secret.ServerName) &&
+			strings.EqualFold(known.secret.AccountName, secret.AccountName) {
+			return i
+		}
+	}
+	return -1
+}
+
+// accountError 保留原错误码，在错误信息中附上出错的账户名称
+func accountError(name string, err error) error {
+	return &apperr.Error{Code: apperr.CodeOf(err), Message: "添加账户 " + name + " 失败", Err: err}
+}
+
+// ImportAegis 导入 Aegis 的 JSON 导出文件，加密的导出文件需要提供导出时设置的密码
+// mode 为重复账户的处理方式，返回新增、跳过和覆盖的账户数量，不支持的账户类型和格式错误的密钥记入 Warnings
+func (a *App) ImportAegis(data []byte, password string, mode string) (ImportSummary, error) {
+	logger.Protect(password)
+	if _, err := a.currentCipher(); err != nil {
+		return ImportSummary{}, err
+	}
+
+	entries, err := aegis.Parse(data, password)
+	if err != nil {
+		return ImportSummary{}, err
+	}
+
+	return a.runImport(mode, func(s *importSession) error {
+		for i, entry := range entries {
+			secret, plaintext, err := aegisEntryToSecret(entry)
+			if err != nil {
+				slog.Warn("跳过 Aegis 账户", "index", i, "err", err)
+				s.summary.Warnings = append(s.summary.Warnings, fmt.Sprintf("第 %d 个账户 %s 未导入: %s", i+1, entry.Name, err))
+				continue
+			}
+
+			if _, err := s.add(secret, plaintext); err != nil {
+				return accountError(entry.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// aegisEntryToSecret 把 Aegis 账户转换为 secret 表中的记录和明文密钥
+func aegisEntryToSecret(entry aegis.Entry) (model.Secret, string, error) {
+	secret := model.Secret{
+		AccountName: entry.Name,
+		ServerName:  entry.Issuer,
+		Algorithm:   entry.Algorithm,
+		Digits:      uint(entry.Digits),
+		Period:      uint(entry.Period),
+	}
+	if secret.Algorithm == "" {
+		secret.Algorithm = model.DefaultAlgorithm
+	}
+	if secret.Digits == 0 {
+		secret.Digits = model.DefaultDigits
+	}
+	if secret.Period == 0 {
+		secret.Period = model.DefaultPeriod
+	}
+
+	switch entry.Type {
+	case "totp":
+		secret.AccountType = model.AccountTypeTOTP
+		return secret, entry.Secret, nil
+	case "hotp":
+		secret.AccountType = model.AccountTypeHOTP
+		secret.Counter = entry.Counter
+		return secret, entry.Secret, nil
+	case "steam":
+		plaintext, err := utils.SteamSecretFromBase32(entry.Secret)
+		if err != nil {
+			return model.Secret{}, "", apperr.Wrap(apperr.InvalidSecret, "Steam 密钥格式错误", err)
+		}
+		secret.AccountType = model.AccountTypeSteam
+		return secret, plaintext, nil
+	default:
+		return model.Secret{}, "", apperr.New(apperr.Unsupported, "不支持的账户类型: "+entry.Type)
+	}
+}
@@ -0,0 +1,77 @@
+// Package logger 配置程序的日志：分级输出、数据目录下按大小轮转的日志文件，以及去掉敏感内容的过滤层
+// 初始化后标准库 log 包的输出也会经过同一个过滤层
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LevelEnv 指定日志级别的环境变量，可选 debug、info、warn、error
+const LevelEnv = "EUTHENTICATOR_LOG_LEVEL"
+
+const (
+	dirName     = "logs"
+	fileName    = "euthenticator.log"
+	maxFileSize = 1 << 20 // 单个日志文件 1 MiB
+	maxBackups  = 3
+)
+
+// Init 把日志写入 dataDir/logs 下的轮转文件，console 不为 nil 时同时输出到 console
+// 返回的 io.Closer 用于退出前关闭日志文件
+func Init(dataDir string, console io.Writer) (io.Closer, error) {
+	level, err := ParseLevel(os.Getenv(LevelEnv))
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := openRotatingFile(filepath.Join(dataDir, dirName, fileName), maxFileSize, maxBackups)
+	if err != nil {
+		return nil, err
+	}
+
+	var w io.Writer = file
+	if console != nil {
+		w = io.MultiWriter(file, console)
+	}
+
+	handler := slog.NewTextHandler(w, &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(newRedactHandler(handler)))
+	return file, nil
+}
+
+// ParseLevel 解析日志级别名称，为空时使用 info
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("未知的日志级别: %s", name)
+	}
+}
+
+// WailsLogger 把 Wails 运行时的日志转到 slog，同样经过敏感内容过滤
+// 实现 github.com/wailsapp/wails/v2/pkg/logger.Logger 接口
+type WailsLogger struct{}
+
+func (WailsLogger) Print(message string)   { slog.Info(message) }
+func (WailsLogger) Trace(message string)   { slog.Debug(message) }
+func (WailsLogger) Debug(message string)   { slog.Debug(message) }
+func (WailsLogger) Info(message string)    { slog.Info(message) }
+func (WailsLogger) Warning(message string) { slog.Warn(message) }
+func (WailsLogger) Error(message string)   { slog.Error(message) }
+
+func (WailsLogger) Fatal(message string) {
+	slog.Error(message)
+	os.Exit(1)
+}
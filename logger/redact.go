@@ -0,0 +1,162 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Redacted 替换敏感内容的占位符
+const Redacted = "[REDACTED]"
+
+// 属性名中包含这些词的值一律不输出
+var sensitiveKeys = []string{"password", "passwd", "secret", "code", "key", "token", "otp", "plaintext", "cipher", "verifier", "salt", "nonce", "uri", "url"}
+
+var (
+	// otpauth 链接中带有明文密钥
+	otpauthPattern = regexp.MustCompile(`(?i)otpauth(-migration)?://\S*`)
+	// 可能是 Base32/Base64 编码的密钥或密文的长串
+	encodedPattern = regexp.MustCompile(`[A-Za-z0-9+=-]{16,}`)
+	// 独立的 6 到 8 位数字，可能是验证码
+	codePattern = regexp.MustCompile(`\b\d{6,8}\b`)
+	// Steam 令牌的 5 位验证码，字符集与 steamguard.go 中的一致
+	steamCodePattern = regexp.MustCompile(`\b[23456789BCDFGHJKMNPQRTVWXY]{5}\b`)
+)
+
+// minProtectedLen 太短的值不登记，避免把普通文字也替换掉
+const minProtectedLen = 4
+
+// protected 登记过的敏感值，日志中出现时会被替换，锁定保险库时清空
+var protected = struct {
+	sync.RWMutex
+	values map[string]struct{}
+}{values: make(map[string]struct{})}
+
+// Protect 登记敏感值（主密码、明文密钥等），之后任何日志中出现这些值都会被替换
+func Protect(values ...string) {
+	protected.Lock()
+	defer protected.Unlock()
+	for _, v := range values {
+		if len(v) >= minProtectedLen {
+			protected.values[v] = struct{}{}
+		}
+	}
+}
+
+// ForgetProtected 清空登记的敏感值，锁定保险库时调用
+func ForgetProtected() {
+	protected.Lock()
+	defer protected.Unlock()
+	protected.values = make(map[string]struct{})
+}
+
+// Scrub 去掉文本中的敏感内容：登记过的值、otpauth 链接、疑似密钥的编码串和验证码
+func Scrub(s string) string {
+	protected.RLock()
+	for v := range protected.values {
+		if strings.Contains(s, v) {
+			s = strings.ReplaceAll(s, v, Redacted)
+		}
+	}
+	protected.RUnlock()
+
+	s = otpauthPattern.ReplaceAllString(s, Redacted)
+	s = encodedPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if looksEncoded(m) {
+			return Redacted
+		}
+		return m
+	})
+	s = codePattern.ReplaceAllString(s, Redacted)
+	return steamCodePattern.ReplaceAllString(s, Redacted)
+}
+
+// looksEncoded 含数字或全为大写的长串视为编码数据，普通的长单词保留
+func looksEncoded(s string) bool {
+	upper := true
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			return true
+		}
+		if unicode.IsLower(r) {
+			upper = false
+		}
+	}
+	return upper
+}
+
+// isSensitiveKey 判断属性名是否表示敏感内容
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range sensitiveKeys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactAttr 按属性名和内容去掉敏感信息，分组会逐层处理
+func redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		attrs := v.Group()
+		out := make([]any, 0, len(attrs))
+		for _, ga := range attrs {
+			out = append(out, redactAttr(ga))
+		}
+		return slog.Group(a.Key, out...)
+	}
+
+	if isSensitiveKey(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Scrub(v.String()))
+	case slog.KindAny:
+		// 错误和其他任意类型的值转为文字后再处理
+		return slog.String(a.Key, Scrub(fmt.Sprint(v.Any())))
+	default:
+		return slog.Attr{Key: a.Key, Value: v}
+	}
+}
+
+// redactHandler 在交给下一级 Handler 之前去掉消息和属性中的敏感内容
+type redactHandler struct {
+	next slog.Handler
+}
+
+func newRedactHandler(next slog.Handler) *redactHandler {
+	return &redactHandler{next: next}
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, Scrub(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redactAttr(a)
+	}
+	return &redactHandler{next: h.next.WithAttrs(redacted)}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name)}
+}
@@ -0,0 +1,165 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestScrub(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		removed []string // 处理后不应再出现的内容
+		kept    []string // 处理后应保留的内容
+	}{
+		{
+			name:    "otpauth 链接",
+			in:      "导入 otpauth://totp/GitHub:alice?secret=JBSWY3DPEHPK3PXP&issuer=GitHub 失败",
+			removed: []string{"otpauth://", "JBSWY3DPEHPK3PXP", "alice"},
+			kept:    []string{"导入", "失败"},
+		},
+		{
+			name:    "迁移链接",
+			in:      "otpauth-migration://offline?data=CjEKCkhlbGxvId6tvu8SGEV4",
+			removed: []string{"otpauth-migration://", "CjEKCkhlbGxvId6tvu8SGEV4"},
+		},
+		{
+			name:    "Base32 密钥",
+			in:      "密钥 JBSWY3DPEHPK3PXP 无效",
+			removed: []string{"JBSWY3DPEHPK3PXP"},
+			kept:    []string{"密钥", "无效"},
+		},
+		{
+			name:    "Base64 密文",
+			in:      "ciphertext kjoCtXc7jYpz6hlhB5+xpdJTCVGmkdBrAUxzvw1xZz3B0oQutEKexqOdnFs=",
+			removed: []string{"kjoCtXc7jYpz6hlhB5+xpdJTCVGmkdBrAUxzvw1xZz3B0oQutEKexqOdnFs="},
+		},
+		{
+			name:    "六位验证码",
+			in:      "生成验证码 123456",
+			removed: []string{"123456"},
+		},
+		{
+			name:    "八位验证码",
+			in:      "code=12345678;",
+			removed: []string{"12345678"},
+		},
+		{
+			name:    "Steam 验证码",
+			in:      "Steam 验证码 2RVKF",
+			removed: []string{"2RVKF"},
+			kept:    []string{"Steam"},
+		},
+		{
+			name: "普通文字",
+			in:   "数据库已升级 version=6 description=internationalization",
+			kept: []string{"数据库已升级 version=6 description=internationalization"},
+		},
+		{
+			name: "较短的数字",
+			in:   "删除了 12345 个账户中的 3 个",
+			kept: []string{"12345", "3 个"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := Scrub(tt.in)
+			for _, s := range tt.removed {
+				if strings.Contains(out, s) {
+					t.Errorf("Scrub(%q) = %q，仍包含 %q", tt.in, out, s)
+				}
+			}
+			for _, s := range tt.kept {
+				if !strings.Contains(out, s) {
+					t.Errorf("Scrub(%q) = %q，缺少 %q", tt.in, out, s)
+				}
+			}
+		})
+	}
+}
+
+func TestProtect(t *testing.T) {
+	t.Cleanup(ForgetProtected)
+
+	Protect("correct horse", "abc")
+	out := Scrub("主密码为 correct horse，备注 abc")
+	if strings.Contains(out, "correct horse") {
+		t.Errorf("登记过的值没有被替换: %q", out)
+	}
+	if !strings.Contains(out, Redacted) {
+		t.Errorf("缺少占位符: %q", out)
+	}
+	if !strings.Contains(out, "abc") {
+		t.Errorf("过短的值不应登记: %q", out)
+	}
+
+	ForgetProtected()
+	if out := Scrub("主密码为 correct horse"); !strings.Contains(out, "correct horse") {
+		t.Errorf("清空后不应再替换: %q", out)
+	}
+}
+
+func TestRedactAttr(t *testing.T) {
+	t.Cleanup(ForgetProtected)
+	Protect("hunter22")
+
+	tests := []struct {
+		name    string
+		attr    slog.Attr
+		removed string
+	}{
+		{"主密码", slog.String("password", "hunter22"), "hunter22"},
+		{"属性名大小写", slog.String("MasterPassword", "anything"), "anything"},
+		{"密文字段", slog.String("EncryptedSecret", "short"), "short"},
+		{"验证码字段", slog.Int("code", 123), "123"},
+		{"链接字段", slog.String("url", "https://example.com"), "example.com"},
+		{"普通字段中的链接", slog.String("input", "otpauth://totp/a?secret=JBSWY3DPEHPK3PXP"), "JBSWY3DPEHPK3PXP"},
+		{"普通字段中的登记值", slog.String("input", "pw=hunter22"), "hunter22"},
+		{"错误", slog.Any("err", errors.New("无法解析 otpauth://totp/a?secret=JBSWY3DPEHPK3PXP")), "JBSWY3DPEHPK3PXP"},
+		{"包装的错误", slog.Any("err", errors.Join(errors.New("外层"), errors.New("密钥 GEZDGNBVGY3TQOJQ 无效"))), "GEZDGNBVGY3TQOJQ"},
+		{"分组中的敏感字段", slog.Group("request", slog.String("token", "abcdefgh"), slog.Int("id", 1)), "abcdefgh"},
+		{"嵌套分组", slog.Group("a", slog.Group("b", slog.String("note", "otpauth://hotp/x?secret=AAAA"))), "otpauth://"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := redactAttr(tt.attr).String()
+			if strings.Contains(out, tt.removed) {
+				t.Errorf("redactAttr(%v) = %s，仍包含 %q", tt.attr, out, tt.removed)
+			}
+		})
+	}
+
+	t.Run("保留普通字段", func(t *testing.T) {
+		group := redactAttr(slog.Group("request", slog.Int("id", 42), slog.String("account", "alice")))
+		out := group.String()
+		if !strings.Contains(out, "id=42") || !strings.Contains(out, "account=alice") {
+			t.Errorf("普通字段被替换: %s", out)
+		}
+		if group.Value.Kind() != slog.KindGroup {
+			t.Errorf("分组被展开为 %v", group.Value.Kind())
+		}
+	})
+}
+
+func TestRedactHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(newRedactHandler(slog.NewTextHandler(&buf, nil)))
+
+	log.With("token", "abcdefgh").WithGroup("import").Info("导入 otpauth://totp/a?secret=JBSWY3DPEHPK3PXP",
+		"secret", "JBSWY3DPEHPK3PXP", "id", 7)
+
+	out := buf.String()
+	for _, s := range []string{"abcdefgh", "JBSWY3DPEHPK3PXP", "otpauth://"} {
+		if strings.Contains(out, s) {
+			t.Errorf("日志中仍包含 %q: %s", s, out)
+		}
+	}
+	if !strings.Contains(out, "import.id=7") {
+		t.Errorf("分组中的普通字段丢失: %s", out)
+	}
+}
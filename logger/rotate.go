@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// rotatingFile 按大小轮转的日志文件，超过 maxSize 时把当前文件改名为 .1，旧的依次后移，最多保留 backups 个
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	backups int
+	file    *os.File
+	size    int64
+}
+
+func openRotatingFile(path string, maxSize int64, backups int) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %w", err)
+	}
+
+	r := &rotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("打开日志文件失败: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("读取日志文件信息失败: %w", err)
+	}
+
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate 关闭当前文件并依次改名，调用方需持有 mu
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	os.Remove(r.backupPath(r.backups))
+	for i := r.backups - 1; i >= 1; i-- {
+		os.Rename(r.backupPath(i), r.backupPath(i+1))
+	}
+	if r.backups > 0 {
+		if err := os.Rename(r.path, r.backupPath(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(r.path); err != nil {
+		return err
+	}
+
+	return r.open()
+}
+
+func (r *rotatingFile) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", r.path, i)
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "test.log")
+	r, err := openRotatingFile(path, 100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	line := strings.Repeat("x", 39) + "\n"
+	for i := 0; i < 10; i++ {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("缺少日志文件 %s: %v", p, err)
+		}
+		if info.Size() > 100 {
+			t.Errorf("%s 大小为 %d，超过上限", p, info.Size())
+		}
+		if info.Mode().Perm() != 0o600 {
+			t.Errorf("%s 权限为 %v", p, info.Mode().Perm())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("保留的旧文件超过 2 个: %v", err)
+	}
+}
+
+func TestRotatingFileAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", 90)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新打开时按已有大小计算，下一次写入超过上限就轮转
+	r, err := openRotatingFile(path, 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := r.Write([]byte("0123456789ab\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := os.ReadFile(path + ".1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backup) != 90 {
+		t.Errorf("旧内容长度为 %d", len(backup))
+	}
+	current, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(current) != "0123456789ab\n" {
+		t.Errorf("当前文件内容为 %q", current)
+	}
+}
+
+func TestRotatingFileWithoutBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	r, err := openRotatingFile(path, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	r.Write([]byte("first line\n"))
+	r.Write([]byte("second\n"))
+
+	current, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(current) != "second\n" {
+		t.Errorf("当前文件内容为 %q", current)
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Errorf("不保留旧文件时不应生成 .1: %v", err)
+	}
+}
+
+func TestRotatingFileClosed(t *testing.T) {
+	r, err := openRotatingFile(filepath.Join(t.TempDir(), "test.log"), 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Write([]byte("x")); err != os.ErrClosed {
+		t.Errorf("关闭后写入返回 %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("重复关闭返回 %v", err)
+	}
+}
@@ -1,8 +1,16 @@
 package main
 
 import (
+	"auth/apperr"
 	"auth/db"
+	"auth/logger"
+	"auth/utils"
 	"embed"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -13,14 +21,47 @@ import (
 var assets embed.FS
 
 func main() {
+	dataDirFlag := flag.String("data-dir", "", "数据目录，默认使用系统的应用数据目录，也可通过环境变量 "+utils.DataDirEnv+" 指定")
+	flag.Parse()
 
-	db.InitDB()
+	// 带子命令启动时以命令行模式运行，不启动图形界面
+	cliMode := flag.NArg() > 0
+
+	dataDir, err := utils.ResolveDataDir(*dataDirFlag)
+	if err != nil {
+		fatal("确定数据目录失败: %v", err)
+	}
+
+	// 命令行模式下日志会干扰命令输出，只写入日志文件
+	var console io.Writer = os.Stderr
+	if cliMode {
+		console = nil
+	}
+	logFile, err := logger.Init(dataDir, console)
+	if err != nil {
+		fatal("初始化日志失败: %v", err)
+	}
+	defer logFile.Close()
+
+	if err := db.MigrateLegacyFile(dataDir); err != nil {
+		fatal("%v", err)
+	}
+
+	if err := db.InitDB(dataDir); err != nil {
+		fatal("%v", err)
+	}
+
+	if cliMode {
+		code := runCLI(flag.Args())
+		logFile.Close()
+		os.Exit(code)
+	}
 
 	// Create an instance of the app structure
 	app := NewApp()
 
 	// Create application with options
-	err := wails.Run(&options.App{
+	err = wails.Run(&options.App{
 		Title:     "Euthenticator",
 		Frameless: true,
 		Width:     1250,
@@ -30,12 +71,20 @@ func main() {
 		},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
 		OnStartup:        app.startup,
+		ErrorFormatter:   apperr.Format,
+		Logger:           logger.WailsLogger{},
 		Bind: []interface{}{
 			app,
 		},
 	})
 
 	if err != nil {
-		println("Error:", err.Error())
+		slog.Error("启动失败", "err", err)
 	}
 }
+
+// fatal 输出错误并退出，日志可能尚未初始化或只写入文件，所以直接写入标准错误
+func fatal(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -1,10 +1,31 @@
 package model
 
+// 账户类型，对应 secret 表中的 account_type 字段
+const (
+	AccountTypeTOTP  = 0
+	AccountTypeSteam = 1
+	AccountTypeHOTP  = 2
+)
+
+// 未指定时使用的 OTP 参数，与 Google Authenticator 保持一致
+const (
+	DefaultAlgorithm = "SHA1"
+	DefaultDigits    = 6
+	DefaultPeriod    = 30
+)
+
 type Secret struct {
 	ID              uint
 	AccountName     string
 	ServerName      string
 	EncryptedSecret string `json:"-"`
-	AccountType     uint   `json:"-"`
+	AccountType     uint
+	Algorithm       string
+	Digits          uint
+	Period          uint
+	Counter         uint64
 	Code            string
+	NextCode        string // 下一个周期的验证码，HOTP 为空
+	Remaining       uint   // 当前验证码剩余的有效秒数，HOTP 为 0
+	ServerTime      int64  // 生成验证码时使用的服务器时间（Unix 秒），前端据此校正本地时钟
 }
@@ -0,0 +1,25 @@
+package model
+
+// 密钥派生算法版本，保存在 vault 表中
+const (
+	KDFVersionSHA256   = 1 // 旧版本：主密码直接做一次 SHA-256，没有盐
+	KDFVersionArgon2id = 2
+)
+
+// KDFParams 主密码派生密钥所需的参数
+type KDFParams struct {
+	Version int
+	Salt    []byte
+	Time    uint32
+	Memory  uint32 // 单位 KiB
+	Threads uint8
+}
+
+// VaultHeader 保险库头信息，保存派生参数和主密码校验值
+type VaultHeader struct {
+	KDF      KDFParams
+	Verifier string
+	VaultID  string // 作为账户密文的关联数据，为空表示旧保险库的密文尚未绑定到所在的行
+
+	EncryptedMetadata bool // 账户名称和服务商是否加密保存，旧保险库中为明文
+}
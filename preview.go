@@ -0,0 +1,193 @@
+package main
+
+import (
+	"auth/apperr"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// previewTTL 导入预览的有效期，过期后需要重新识别二维码
+const previewTTL = 10 * time.Minute
+
+// importPreview 已解析但尚未确认的导入内容
+type importPreview struct {
+	accounts   []uriAccount
+	createdAt  time.Time
+	committing bool // 正在导入，避免同一个预览被同时提交两次
+}
+
+// ImportPreviewEntry 预览中的一个账户，不包含密钥
+type ImportPreviewEntry struct {
+	Index     int    `json:"index"`
+	Name      string `json:"name"`
+	Issuer    string `json:"issuer"`
+	Type      string `json:"type"`
+	Algorithm string `json:"algorithm"`
+	Digits    uint   `json:"digits"`
+	Period    uint   `json:"period"`
+	Counter   uint64 `json:"counter"`
+	Duplicate bool   `json:"duplicate"`       // 保险库中已存在相同的账户
+	Error     string `json:"error,omitempty"` // 校验失败的原因，这类账户无法导入
+}
+
+// ImportPreview 二维码的预览结果，确认导入时需要提供 Token
+type ImportPreview struct {
+	Token    string               `json:"token"`
+	Entries  []ImportPreviewEntry `json:"entries"`
+	Warnings []string             `json:"warnings,omitempty"` // 部分二维码无法识别、导出码缺页等问题
+	Strategy string               `json:"strategy"`           // 识别成功时使用的图像预处理方式
+}
+
+// PreviewImport 识别图片中的所有二维码并返回其中的账户列表，不写入数据库
+// 之后调用 CommitImport 导入选中的账户
+func (a *App) PreviewImport(imgBytes []byte) (ImportPreview, error) {
+	if _, err := a.currentCipher(); err != nil {
+		return ImportPreview{}, err
+	}
+
+	texts, strategy, err := decodeQRCodes(imgBytes)
+	if err != nil {
+		return ImportPreview{}, err
+	}
+
+	parsed, err := parseQRTexts(texts)
+	if err != nil {
+		return ImportPreview{}, err
+	}
+	accounts := parsed.accounts
+
+	entries, err := a.previewEntries(accounts)
+	if err != nil {
+		return ImportPreview{}, err
+	}
+
+	token, err := newPreviewToken()
+	if err != nil {
+		return ImportPreview{}, apperr.Wrap(apperr.Internal, "生成导入会话失败", err)
+	}
+
+	a.previewMu.Lock()
+	defer a.previewMu.Unlock()
+	a.expirePreviews()
+	a.previews[token] = importPreview{accounts: accounts, createdAt: time.Now()}
+
+	return ImportPreview{Token: token, Entries: entries, Warnings: parsed.warnings, Strategy: strategy}, nil
+}
+
+// previewEntries 校验每个账户并标记已存在的账户
+func (a *App) previewEntries(accounts []uriAccount) ([]ImportPreviewEntry, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.cipher == nil {
+		return nil, ErrVaultLocked
+	}
+
+	s, err := newImportSession(a.cipher, DuplicateSkip)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]ImportPreviewEntry, 0, len(accounts))
+	for i, account := range accounts {
+		entry := ImportPreviewEntry{
+			Index:     i,
+			Name:      account.secret.AccountName,
+			Issuer:    account.secret.ServerName,
+			Type:      accountTypeName(account.secret.AccountType),
+			Algorithm: account.secret.Algorithm,
+			Digits:    account.secret.Digits,
+			Period:    account.secret.Period,
+			Counter:   account.secret.Counter,
+		}
+
+		secret, plaintext, err := normalizeSecret(account.secret, account.plaintext)
+		if err != nil {
+			entry.Error = err.Error()
+		} else {
+			entry.Algorithm = secret.Algorithm
+			entry.Digits = secret.Digits
+			entry.Period = secret.Period
+			entry.Duplicate = s.findDuplicate(secret, plaintext) >= 0
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// CommitImport 导入预览中选中的账户，indexes 为 ImportPreviewEntry.Index，mode 为重复账户的处理方式
+// 导入成功后预览失效；导入失败时预览保留，可以调整选择后再次提交
+func (a *App) CommitImport(token string, indexes []int, mode string) (ImportSummary, error) {
+	if len(indexes) == 0 {
+		return ImportSummary{}, apperr.New(apperr.InvalidArgument, "请选择要导入的账户")
+	}
+
+	a.previewMu.Lock()
+	a.expirePreviews()
+	preview, ok := a.previews[token]
+	if !ok {
+		a.previewMu.Unlock()
+		return ImportSummary{}, apperr.New(apperr.NotFound, "导入预览不存在或已过期，请重新识别二维码")
+	}
+	if preview.committing {
+		a.previewMu.Unlock()
+		return ImportSummary{}, apperr.New(apperr.InvalidArgument, "正在导入这些账户，请稍候")
+	}
+	for _, i := range indexes {
+		if i < 0 || i >= len(preview.accounts) {
+			a.previewMu.Unlock()
+			return ImportSummary{}, apperr.New(apperr.InvalidArgument, "选择的账户不在预览中")
+		}
+	}
+	preview.committing = true
+	a.previews[token] = preview
+	a.previewMu.Unlock()
+
+	// 导入期间不持有 previewMu：runImport 会获取 a.mu，而 Lock 持有 a.mu 时会调用 clearPreviews
+	summary, err := a.runImport(mode, func(s *importSession) error {
+		for _, i := range indexes {
+			account := preview.accounts[i]
+			if _, err := s.add(account.secret, account.plaintext); err != nil {
+				return accountError(account.secret.AccountName, err)
+			}
+		}
+		return nil
+	})
+
+	a.previewMu.Lock()
+	defer a.previewMu.Unlock()
+	if err == nil {
+		delete(a.previews, token)
+	} else if current, ok := a.previews[token]; ok {
+		// 锁定保险库时预览已被清除，不再恢复
+		current.committing = false
+		a.previews[token] = current
+	}
+	return summary, err
+}
+
+// expirePreviews 删除过期的预览，正在导入的预览等导入结束后再处理，调用方需持有 previewMu
+func (a *App) expirePreviews() {
+	for token, preview := range a.previews {
+		if !preview.committing && time.Since(preview.createdAt) > previewTTL {
+			delete(a.previews, token)
+		}
+	}
+}
+
+// clearPreviews 删除所有预览，锁定保险库时调用，避免明文密钥留在内存中
+func (a *App) clearPreviews() {
+	a.previewMu.Lock()
+	defer a.previewMu.Unlock()
+	a.previews = make(map[string]importPreview)
+}
+
+// newPreviewToken 生成随机的预览标识
+func newPreviewToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
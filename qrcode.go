@@ -0,0 +1,186 @@
+package main
+
+import (
+	"auth/apperr"
+	gotp "auth/utils/otp_extractor"
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"  // 支持GIF格式
+	_ "image/jpeg" // 支持JPEG格式
+	_ "image/png"  // 支持PNG格式
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/makiuchi-d/gozxing"
+	multiqr "github.com/makiuchi-d/gozxing/multi/qrcode"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	_ "golang.org/x/image/bmp"  // 支持BMP格式
+	_ "golang.org/x/image/webp" // 支持WebP格式
+)
+
+// qrImport 一张图片中所有二维码解析出的账户
+type qrImport struct {
+	accounts []uriAccount
+	warnings []string
+}
+
+// migrationGroup 同一次谷歌验证器导出的多张二维码
+type migrationGroup struct {
+	size  int32
+	parts map[int32][]uriAccount
+}
+
+// decodeQRCodes 识别图片中的所有二维码，返回其中的文本和识别成功时使用的预处理方式，同一内容只返回一次
+func decodeQRCodes(imgBytes []byte) ([]string, string, error) {
+	// 先读取尺寸，过大的图片在解码、转灰度和旋转时都会占用大量内存
+	config, format, err := image.DecodeConfig(bytes.NewReader(imgBytes))
+	if err != nil {
+		slog.Warn("图像解码失败", "format", format, "err", err)
+		return nil, "", apperr.Wrap(apperr.Unsupported, "无法解码图像，支持 PNG、JPEG、GIF、WebP 和 BMP 格式", err)
+	}
+	if int64(config.Width)*int64(config.Height) > qrMaxPixels {
+		slog.Warn("图片尺寸超出限制", "format", format, "width", config.Width, "height", config.Height)
+		return nil, "", apperr.New(apperr.Unsupported, "图片尺寸过大，请裁剪出二维码所在区域后重试")
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(imgBytes))
+	if err != nil {
+		slog.Warn("图像解码失败", "format", format, "err", err)
+		return nil, "", apperr.Wrap(apperr.Unsupported, "无法解码图像，支持 PNG、JPEG、GIF、WebP 和 BMP 格式", err)
+	}
+
+	gray := grayImage(img)
+	for _, strategy := range qrStrategies {
+		prepared := strategy.prepare(gray)
+		if prepared == nil {
+			continue
+		}
+
+		texts, err := decodeQRImage(prepared, strategy.tryHarder)
+		if err != nil {
+			slog.Debug("二维码识别失败", "strategy", strategy.name, "err", err)
+			continue
+		}
+
+		slog.Info("识别二维码", "format", format, "strategy", strategy.name, "count", len(texts))
+		return texts, strategy.name, nil
+	}
+
+	return nil, "", apperr.New(apperr.Unsupported, "图片中没有找到可识别的二维码")
+}
+
+// decodeQRImage 识别一张预处理后的图片中的所有二维码
+func decodeQRImage(img image.Image, tryHarder bool) ([]string, error) {
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return nil, err
+	}
+
+	var hints map[gozxing.DecodeHintType]interface{}
+	if tryHarder {
+		hints = map[gozxing.DecodeHintType]interface{}{gozxing.DecodeHintType_TRY_HARDER: true}
+	}
+
+	// 谷歌验证器导出多张二维码时，用户常把它们截在同一张图里
+	results, err := multiqr.NewQRCodeMultiReader().DecodeMultiple(bmp, hints)
+	if err != nil || len(results) == 0 {
+		// 多码识别要求找到完整的定位图案，单码识别对模糊的图片更宽容
+		result, err := qrcode.NewQRCodeReader().Decode(bmp, hints)
+		if err != nil {
+			return nil, err
+		}
+		results = []*gozxing.Result{result}
+	}
+
+	seen := make(map[string]bool, len(results))
+	texts := make([]string, 0, len(results))
+	for _, result := range results {
+		text := result.GetText()
+		if !seen[text] {
+			seen[text] = true
+			texts = append(texts, text)
+		}
+	}
+
+	return texts, nil
+}
+
+// parseQRTexts 解析多个二维码的文本，迁移二维码按 batch_id 分组并按页码排序
+// 部分二维码无法识别或导出码缺页时记入 warnings，全部无法识别时返回错误
+func parseQRTexts(texts []string) (qrImport, error) {
+	var result qrImport
+	var firstErr error
+	groups := make(map[int32]*migrationGroup)
+	var groupOrder []int32
+
+	for i, text := range texts {
+		if !strings.HasPrefix(text, "otpauth-migration://") {
+			accounts, err := parseURI(text)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				result.warnings = append(result.warnings, fmt.Sprintf("第 %d 个二维码无法识别: %s", i+1, err))
+				continue
+			}
+			result.accounts = append(result.accounts, accounts...)
+			continue
+		}
+
+		batch, err := gotp.ExtractMigrationBatch(text)
+		if err != nil {
+			err = apperr.Wrap(apperr.Unsupported, "解析谷歌验证器导出码失败", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			result.warnings = append(result.warnings, fmt.Sprintf("第 %d 个二维码无法识别: %s", i+1, err))
+			continue
+		}
+
+		group, ok := groups[batch.ID]
+		if !ok {
+			group = &migrationGroup{size: batch.Size, parts: make(map[int32][]uriAccount)}
+			groups[batch.ID] = group
+			groupOrder = append(groupOrder, batch.ID)
+		}
+		group.parts[batch.Index] = migrationAccounts(batch.Entries)
+	}
+
+	for _, id := range groupOrder {
+		group := groups[id]
+		indexes := make([]int32, 0, len(group.parts))
+		for index := range group.parts {
+			indexes = append(indexes, index)
+		}
+		sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+		for _, index := range indexes {
+			result.accounts = append(result.accounts, group.parts[index]...)
+		}
+
+		if missing := group.missing(); len(missing) > 0 {
+			result.warnings = append(result.warnings, fmt.Sprintf("谷歌验证器导出码共 %d 张，缺少第 %s 张，请另外导入", group.size, strings.Join(missing, "、")))
+		}
+	}
+
+	if len(result.accounts) == 0 {
+		if firstErr != nil {
+			return qrImport{}, firstErr
+		}
+		return qrImport{}, apperr.New(apperr.Unsupported, "二维码中没有账户")
+	}
+
+	return result, nil
+}
+
+// missing 返回缺少的页码，从 1 开始
+func (g *migrationGroup) missing() []string {
+	var missing []string
+	for i := int32(0); i < g.size; i++ {
+		if _, ok := g.parts[i]; !ok {
+			missing = append(missing, fmt.Sprint(i+1))
+		}
+	}
+	return missing
+}
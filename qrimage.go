@@ -0,0 +1,143 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	stddraw "image/draw"
+	"math"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+const (
+	// 小于该尺寸的图片在识别前放大，手机截图中的二维码常常只有一百多像素
+	qrMinSize = 600
+	// 放大后较长一边的上限，避免细长的图片放大后占用过多内存
+	qrMaxScaledSize = 4000
+	// 解码前检查的像素数上限，约为 6000×4000 的照片
+	qrMaxPixels = 24_000_000
+)
+
+// qrStrategy 一种识别二维码前的图像预处理方式
+type qrStrategy struct {
+	name      string
+	tryHarder bool
+	// prepare 返回预处理后的图片，返回 nil 表示该方式不适用于这张图片
+	prepare func(img *image.Gray) image.Image
+}
+
+// qrStrategies 依次尝试的预处理方式，代价小的在前
+var qrStrategies = []qrStrategy{
+	{name: "原图", prepare: func(img *image.Gray) image.Image { return img }},
+	{name: "增强识别", tryHarder: true, prepare: func(img *image.Gray) image.Image { return img }},
+	{name: "反色", tryHarder: true, prepare: invertImage},
+	{name: "放大", tryHarder: true, prepare: upscaleImage},
+	{name: "二值化", tryHarder: true, prepare: thresholdImage},
+	{name: "旋转 45°", tryHarder: true, prepare: func(img *image.Gray) image.Image { return rotateImage(img, 45) }},
+	{name: "旋转 90°", tryHarder: true, prepare: func(img *image.Gray) image.Image { return rotateImage(img, 90) }},
+	{name: "旋转 135°", tryHarder: true, prepare: func(img *image.Gray) image.Image { return rotateImage(img, 135) }},
+}
+
+// grayImage 把图片转换为灰度图，透明背景按白色处理
+func grayImage(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	stddraw.Draw(gray, gray.Bounds(), image.White, image.Point{}, stddraw.Src)
+	stddraw.Draw(gray, gray.Bounds(), img, bounds.Min, stddraw.Over)
+	return gray
+}
+
+// invertImage 反色，用于深色模式下浅色前景的二维码
+func invertImage(img *image.Gray) image.Image {
+	inverted := image.NewGray(img.Rect)
+	for i, v := range img.Pix {
+		inverted.Pix[i] = 255 - v
+	}
+	return inverted
+}
+
+// upscaleImage 放大较小的图片，图片足够大或放大后超过 qrMaxScaledSize 时返回 nil
+func upscaleImage(img *image.Gray) image.Image {
+	size := min(img.Rect.Dx(), img.Rect.Dy())
+	if size == 0 || size >= qrMinSize {
+		return nil
+	}
+
+	scale := min((qrMinSize+size-1)/size, qrMaxScaledSize/max(img.Rect.Dx(), img.Rect.Dy()))
+	if scale < 2 {
+		return nil
+	}
+	scaled := image.NewGray(image.Rect(0, 0, img.Rect.Dx()*scale, img.Rect.Dy()*scale))
+	draw.CatmullRom.Scale(scaled, scaled.Rect, img, img.Rect, draw.Src, nil)
+	return scaled
+}
+
+// thresholdImage 用大津法选取阈值转换为黑白图片，用于光照不均、对比度低的照片
+func thresholdImage(img *image.Gray) image.Image {
+	var histogram [256]int
+	for _, v := range img.Pix {
+		histogram[v]++
+	}
+	threshold := otsuThreshold(histogram, len(img.Pix))
+
+	binary := image.NewGray(img.Rect)
+	for i, v := range img.Pix {
+		if v > threshold {
+			binary.Pix[i] = 255
+		}
+	}
+	return binary
+}
+
+// otsuThreshold 返回使前景和背景类间方差最大的灰度阈值
+func otsuThreshold(histogram [256]int, total int) uint8 {
+	var sum float64
+	for v, n := range histogram {
+		sum += float64(v * n)
+	}
+
+	var sumBackground, best float64
+	var background int
+	var threshold uint8
+	for v, n := range histogram {
+		background += n
+		if background == 0 {
+			continue
+		}
+		foreground := total - background
+		if foreground == 0 {
+			break
+		}
+
+		sumBackground += float64(v * n)
+		meanBackground := sumBackground / float64(background)
+		meanForeground := (sum - sumBackground) / float64(foreground)
+		variance := float64(background) * float64(foreground) * (meanBackground - meanForeground) * (meanBackground - meanForeground)
+		if variance > best {
+			best = variance
+			threshold = uint8(v)
+		}
+	}
+	return threshold
+}
+
+// rotateImage 绕中心顺时针旋转图片，画布扩大到能容纳整张图片，空白处填充白色
+func rotateImage(img *image.Gray, degrees float64) image.Image {
+	radians := degrees * math.Pi / 180
+	sin, cos := math.Sin(radians), math.Cos(radians)
+	w, h := float64(img.Rect.Dx()), float64(img.Rect.Dy())
+	newW := math.Ceil(math.Abs(w*cos) + math.Abs(h*sin))
+	newH := math.Ceil(math.Abs(w*sin) + math.Abs(h*cos))
+
+	rotated := image.NewGray(image.Rect(0, 0, int(newW), int(newH)))
+	stddraw.Draw(rotated, rotated.Rect, &image.Uniform{C: color.White}, image.Point{}, stddraw.Src)
+
+	// 源图片中心平移到原点，旋转后再平移到新画布中心
+	m := f64.Aff3{
+		cos, -sin, newW/2 - cos*w/2 + sin*h/2,
+		sin, cos, newH/2 - sin*w/2 - cos*h/2,
+	}
+	draw.BiLinear.Transform(rotated, m, img, img.Rect, draw.Over, nil)
+	return rotated
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"auth/apperr"
+	"auth/db"
+	"log/slog"
+)
+
+// QuarantinedAccount 升级保险库时无法用当前主密码解密、已移出账户列表的账户
+type QuarantinedAccount struct {
+	ID     uint   `json:"id"`
+	Name   string `json:"name"`
+	Issuer string `json:"issuer"`
+}
+
+// GetQuarantinedAccounts 返回已移出账户列表的账户，解锁后由界面提示用户
+func (a *App) GetQuarantinedAccounts() ([]QuarantinedAccount, error) {
+	c, err := a.currentCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	secrets, err := db.GetQuarantinedSecrets()
+	if err != nil {
+		return nil, apperr.Wrap(apperr.DBFailure, "读取无法解密的账户失败", err)
+	}
+
+	accounts := make([]QuarantinedAccount, 0, len(secrets))
+	for _, secret := range secrets {
+		account := QuarantinedAccount{ID: secret.ID}
+		if opened, err := c.OpenMetadata(secret); err == nil {
+			account.Name, account.Issuer = opened.AccountName, opened.ServerName
+		} else {
+			slog.Warn("解密账户名称失败", "id", secret.ID, "err", err)
+			account.Name = "（无法解密）"
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// DeleteQuarantinedAccounts 删除所有已移出账户列表的账户
+func (a *App) DeleteQuarantinedAccounts() error {
+	if _, err := a.currentCipher(); err != nil {
+		return err
+	}
+
+	if err := db.DeleteQuarantined(); err != nil {
+		slog.Error("删除无法解密的账户失败", "err", err)
+		return apperr.Wrap(apperr.DBFailure, "删除无法解密的账户失败", err)
+	}
+	return nil
+}
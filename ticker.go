@@ -0,0 +1,195 @@
+package main
+
+import (
+	"auth/db"
+	"auth/model"
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// CodesEvent 有账户进入新的验证码周期时发送给前端的事件，数据为 []CodeUpdate
+const CodesEvent = "codes:refresh"
+
+// tickerMaxWait 计时器最长的等待时间，用于在系统休眠、调整时钟后及时发现周期变化
+const tickerMaxWait = 5 * time.Second
+
+// CodeUpdate 一个账户的新验证码，字段与 model.Secret 中的同名字段一致
+type CodeUpdate struct {
+	ID         uint
+	Code       string
+	NextCode   string
+	Remaining  uint
+	ServerTime int64
+}
+
+// tickerAccount 计时器缓存的账户，避免每个周期都重新读取和解密
+type tickerAccount struct {
+	secret    model.Secret
+	plaintext string
+	window    int64 // 最近一次计算验证码时所在的周期序号
+}
+
+// SetWindowVisible 前端在窗口显示或隐藏时调用，窗口隐藏期间停止推送验证码
+func (a *App) SetWindowVisible(visible bool) {
+	a.tickerMu.Lock()
+	defer a.tickerMu.Unlock()
+
+	a.windowHidden = !visible
+	a.updateTicker()
+}
+
+// invalidateCodes 账户发生变化后调用，计时器在下次唤醒时重新读取账户
+func (a *App) invalidateCodes() {
+	a.codesDirty.Store(true)
+}
+
+// restartTicker 保险库解锁或锁定后调用，按当前状态启动或停止计时器
+func (a *App) restartTicker() {
+	a.tickerMu.Lock()
+	defer a.tickerMu.Unlock()
+
+	a.updateTicker()
+}
+
+// updateTicker 只在图形界面中、保险库已解锁且窗口可见时运行计时器，调用方需持有 tickerMu
+func (a *App) updateTicker() {
+	a.mu.RLock()
+	unlocked := a.cipher != nil
+	a.mu.RUnlock()
+
+	run := a.ctx != nil && unlocked && !a.windowHidden
+	if run == (a.tickerCancel != nil) {
+		return
+	}
+
+	if !run {
+		a.tickerCancel()
+		a.tickerCancel = nil
+		slog.Debug("停止验证码计时器")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.tickerCancel = cancel
+	a.codesDirty.Store(true)
+	go a.runTicker(ctx)
+	slog.Debug("启动验证码计时器")
+}
+
+// runTicker 在最近的周期边界唤醒，只把进入新周期的账户的验证码推送给前端
+func (a *App) runTicker(ctx context.Context) {
+	var accounts []tickerAccount
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		if a.codesDirty.Swap(false) {
+			loaded, err := a.loadTickerAccounts()
+			if err != nil {
+				slog.Warn("计时器读取账户失败", "err", err)
+				a.codesDirty.Store(true)
+			} else {
+				// 无法确定前端的列表是在哪个周期获取的，重新读取后推送所有账户的当前验证码
+				accounts = loaded
+			}
+		}
+
+		updates := tickAccounts(accounts, now)
+		if len(updates) > 0 && ctx.Err() == nil {
+			runtime.EventsEmit(a.ctx, CodesEvent, updates)
+		}
+
+		timer.Reset(nextBoundary(accounts, now))
+	}
+}
+
+// loadTickerAccounts 读取并解密所有按时间变化的账户，HOTP 账户不需要计时
+// 返回的账户周期序号为 -1，下一次 tickAccounts 会为每个账户生成验证码
+func (a *App) loadTickerAccounts() ([]tickerAccount, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.cipher == nil {
+		return nil, ErrVaultLocked
+	}
+
+	secrets, err := db.GetSecretsList()
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]tickerAccount, 0, len(secrets))
+	for _, secret := range secrets {
+		secret.Period = codePeriod(secret)
+		if secret.Period == 0 {
+			continue
+		}
+
+		secret, plaintext, err := a.cipher.OpenAccount(secret)
+		if err != nil {
+			slog.Warn("解密失败", "id", secret.ID, "err", err)
+			continue
+		}
+		accounts = append(accounts, tickerAccount{
+			secret:    secret,
+			plaintext: plaintext,
+			window:    -1,
+		})
+	}
+	return accounts, nil
+}
+
+// tickAccounts 重新生成进入新周期的账户的验证码
+func tickAccounts(accounts []tickerAccount, now time.Time) []CodeUpdate {
+	var updates []CodeUpdate
+	for i := range accounts {
+		account := &accounts[i]
+		period := int64(account.secret.Period)
+		window := now.Unix() / period
+		if window == account.window {
+			continue
+		}
+		account.window = window
+
+		code, err := generateCode(account.secret, account.plaintext, now)
+		if err != nil {
+			slog.Warn("生成验证码失败", "id", account.secret.ID, "err", err)
+			continue
+		}
+		remaining := period - now.Unix()%period
+		nextCode, err := generateCode(account.secret, account.plaintext, time.Unix(now.Unix()+remaining, 0))
+		if err != nil {
+			slog.Warn("生成验证码失败", "id", account.secret.ID, "err", err)
+		}
+
+		updates = append(updates, CodeUpdate{
+			ID:         account.secret.ID,
+			Code:       code,
+			NextCode:   nextCode,
+			Remaining:  uint(remaining),
+			ServerTime: now.Unix(),
+		})
+	}
+	return updates
+}
+
+// nextBoundary 返回距离最近一个账户进入新周期的时间，最长为 tickerMaxWait
+func nextBoundary(accounts []tickerAccount, now time.Time) time.Duration {
+	wait := tickerMaxWait
+	for _, account := range accounts {
+		boundary := time.Unix((account.window+1)*int64(account.secret.Period), 0)
+		if d := boundary.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	return max(wait, 0)
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"auth/apperr"
+	"auth/model"
+	"auth/utils"
+	gotp "auth/utils/otp_extractor"
+	"bufio"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// URIImportResult 一行链接的导入结果，Error 为空表示该行全部导入成功
+type URIImportResult struct {
+	Line    int      `json:"line"`
+	Added   []string `json:"added"`   // 新增的账户名称
+	Skipped []string `json:"skipped"` // 因重复而跳过的账户名称
+	Updated []string `json:"updated"` // 覆盖了已有账户的账户名称
+	Code    string   `json:"code,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// URIImportReport 粘贴链接的导入结果，包括总计和每一行的结果
+type URIImportReport struct {
+	Summary ImportSummary     `json:"summary"`
+	Lines   []URIImportResult `json:"lines"`
+}
+
+// uriAccount 从链接中解析出的账户和明文密钥
+type uriAccount struct {
+	secret    model.Secret
+	plaintext string
+}
+
+// ImportURI 导入粘贴的 otpauth:// 或 otpauth-migration:// 链接，每行一个
+// mode 为重复账户的处理方式，出错的行不影响其他行的导入
+func (a *App) ImportURI(text string, mode string) (URIImportReport, error) {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	// 谷歌验证器的迁移链接可能很长
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSpace(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		return URIImportReport{}, apperr.Wrap(apperr.InvalidArgument, "读取链接失败", err)
+	}
+
+	var report URIImportReport
+	summary, err := a.runImport(mode, func(s *importSession) error {
+		for i, uri := range lines {
+			if uri == "" {
+				continue
+			}
+
+			result := URIImportResult{Line: i + 1, Added: []string{}, Skipped: []string{}, Updated: []string{}}
+			if err := s.addURI(uri, &result); err != nil {
+				result.Code = string(apperr.CodeOf(err))
+				result.Error = err.Error()
+			}
+			report.Lines = append(report.Lines, result)
+		}
+		if len(report.Lines) == 0 {
+			return apperr.New(apperr.InvalidArgument, "没有找到可导入的链接")
+		}
+		return nil
+	})
+	if err != nil {
+		return URIImportReport{}, err
+	}
+
+	report.Summary = summary
+	return report, nil
+}
+
+// addURI 解析一条链接并把其中的账户加入导入过程，每个账户的处理结果记录在 result 中
+// 迁移链接中某个账户出错时停止，之前加入的账户仍会导入
+func (s *importSession) addURI(uri string, result *URIImportResult) error {
+	accounts, err := parseURI(uri)
+	if err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		name := account.secret.AccountName
+		action, err := s.add(account.secret, account.plaintext)
+		if err != nil {
+			return accountError(name, err)
+		}
+
+		switch action {
+		case importAdded:
+			result.Added = append(result.Added, name)
+		case importSkipped:
+			result.Skipped = append(result.Skipped, name)
+		case importUpdated:
+			result.Updated = append(result.Updated, name)
+		}
+	}
+
+	return nil
+}
+
+// parseURI 解析 otpauth:// 或谷歌验证器的 otpauth-migration:// 链接
+func parseURI(uri string) ([]uriAccount, error) {
+	switch {
+	case strings.HasPrefix(uri, "otpauth-migration://"):
+		entries, err := gotp.ExtractOtpFromUrl(uri)
+		if err != nil {
+			return nil, apperr.Wrap(apperr.Unsupported, "解析谷歌验证器导出码失败", err)
+		}
+		return migrationAccounts(entries), nil
+
+	case strings.HasPrefix(uri, "otpauth://"):
+		account, err := parseOtpauthURI(uri)
+		if err != nil {
+			return nil, err
+		}
+		return []uriAccount{account}, nil
+
+	default:
+		return nil, apperr.New(apperr.Unsupported, "不是 otpauth:// 或 otpauth-migration:// 链接")
+	}
+}
+
+// migrationAccounts 把迁移链接中的账户转换为待导入的账户
+func migrationAccounts(entries []gotp.OtpEntry) []uriAccount {
+	accounts := make([]uriAccount, 0, len(entries))
+	for _, entry := range entries {
+		otpType := uint(model.AccountTypeTOTP)
+		if entry.Type == "hotp" {
+			otpType = model.AccountTypeHOTP
+		}
+		accounts = append(accounts, uriAccount{
+			secret: model.Secret{
+				AccountName: entry.Name,
+				ServerName:  entry.Issuer,
+				AccountType: otpType,
+				Algorithm:   entry.Algorithm,
+				Digits:      uint(entry.Digits),
+				Period:      uint(entry.Period),
+				Counter:     uint64(entry.Counter),
+			},
+			plaintext: entry.Secret,
+		})
+	}
+	return accounts
+}
+
+// parseOtpauthURI 解析 otpauth://TYPE/LABEL?PARAMS 格式的链接
+// 支持 totp、hotp，以及 Aegis 等使用的 otpauth://steam 和 encoder=steam 参数
+func parseOtpauthURI(uri string) (uriAccount, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uriAccount{}, apperr.Wrap(apperr.InvalidArgument, "链接格式错误", err)
+	}
+	query := u.Query()
+
+	// 标签格式为 "发行者:账户名" 或 "账户名"，issuer 参数优先
+	label := strings.TrimPrefix(u.Path, "/")
+	issuer := query.Get("issuer")
+	name := label
+	if i := strings.Index(label, ":"); i >= 0 {
+		if issuer == "" {
+			issuer = strings.TrimSpace(label[:i])
+		}
+		name = label[i+1:]
+	}
+
+	account := uriAccount{
+		secret: model.Secret{
+			AccountName: strings.TrimSpace(name),
+			ServerName:  issuer,
+			Algorithm:   strings.ToUpper(query.Get("algorithm")),
+		},
+		plaintext: query.Get("secret"),
+	}
+	if account.plaintext == "" {
+		return uriAccount{}, apperr.New(apperr.InvalidSecret, "链接中缺少 secret 参数")
+	}
+
+	digits, err := uintParam(query, "digits")
+	if err != nil {
+		return uriAccount{}, err
+	}
+	period, err := uintParam(query, "period")
+	if err != nil {
+		return uriAccount{}, err
+	}
+	account.secret.Digits = uint(digits)
+	account.secret.Period = uint(period)
+
+	otpType := strings.ToLower(u.Host)
+	if strings.EqualFold(query.Get("encoder"), "steam") {
+		otpType = "steam"
+	}
+	switch otpType {
+	case "totp":
+		account.secret.AccountType = model.AccountTypeTOTP
+	case "hotp":
+		if !query.Has("counter") {
+			return uriAccount{}, apperr.New(apperr.InvalidArgument, "HOTP 链接中缺少 counter 参数")
+		}
+		counter, err := uintParam(query, "counter")
+		if err != nil {
+			return uriAccount{}, err
+		}
+		account.secret.AccountType = model.AccountTypeHOTP
+		account.secret.Counter = counter
+	case "steam":
+		// 链接中的 Steam 密钥是 Base32，本地保存为 Steam Guard 使用的 Base64
+		plaintext, err := utils.SteamSecretFromBase32(account.plaintext)
+		if err != nil {
+			return uriAccount{}, apperr.Wrap(apperr.InvalidSecret, "Steam 密钥格式错误", err)
+		}
+		account.secret.AccountType = model.AccountTypeSteam
+		account.plaintext = plaintext
+	default:
+		return uriAccount{}, apperr.New(apperr.Unsupported, "不支持的账户类型: "+u.Host)
+	}
+
+	return account, nil
+}
+
+// buildOtpauthURI 把账户还原为标准的 otpauth:// 链接，是 parseOtpauthURI 的逆过程
+// Steam 令牌使用 otpauth://totp 和 encoder=steam 参数，密钥转换为 Base32
+func buildOtpauthURI(secret model.Secret, plaintext string) (string, error) {
+	query := url.Values{}
+	query.Set("algorithm", secret.Algorithm)
+	query.Set("digits", strconv.FormatUint(uint64(secret.Digits), 10))
+
+	otpType := "totp"
+	switch secret.AccountType {
+	case model.AccountTypeTOTP:
+		query.Set("period", strconv.FormatUint(uint64(secret.Period), 10))
+	case model.AccountTypeHOTP:
+		otpType = "hotp"
+		query.Set("counter", strconv.FormatUint(secret.Counter, 10))
+	case model.AccountTypeSteam:
+		base32Secret, err := utils.SteamSecretToBase32(plaintext)
+		if err != nil {
+			return "", apperr.Wrap(apperr.InvalidSecret, "Steam 密钥格式错误", err)
+		}
+		plaintext = base32Secret
+		query.Set("digits", "5")
+		query.Set("period", strconv.FormatUint(uint64(secret.Period), 10))
+		query.Set("encoder", "steam")
+	default:
+		return "", apperr.New(apperr.Unsupported, fmt.Sprintf("不支持的账户类型: %d", secret.AccountType))
+	}
+	query.Set("secret", plaintext)
+
+	label := secret.AccountName
+	if secret.ServerName != "" {
+		query.Set("issuer", secret.ServerName)
+		label = secret.ServerName + ":" + secret.AccountName
+	}
+
+	u := url.URL{Scheme: "otpauth", Host: otpType, Path: "/" + label, RawQuery: query.Encode()}
+	return u.String(), nil
+}
+
+// uintParam 读取链接中的非负整数参数，参数不存在时返回 0
+func uintParam(query url.Values, name string) (uint64, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, apperr.New(apperr.InvalidArgument, fmt.Sprintf("链接参数 %s 不是有效的数字: %q", name, value))
+	}
+	return n, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"auth/apperr"
+	"auth/model"
+	"testing"
+)
+
+func TestParseOtpauthURI(t *testing.T) {
+	tests := []struct {
+		name      string
+		uri       string
+		secret    model.Secret
+		plaintext string
+	}{
+		{
+			name:      "TOTP",
+			uri:       "otpauth://totp/GitHub:alice?secret=JBSWY3DPEHPK3PXP&issuer=GitHub&algorithm=sha256&digits=8&period=60",
+			secret:    model.Secret{AccountName: "alice", ServerName: "GitHub", AccountType: model.AccountTypeTOTP, Algorithm: "SHA256", Digits: 8, Period: 60},
+			plaintext: "JBSWY3DPEHPK3PXP",
+		},
+		{
+			name:      "issuer 参数优先于标签",
+			uri:       "otpauth://totp/Old%20Name:%20alice?secret=JBSWY3DPEHPK3PXP&issuer=New",
+			secret:    model.Secret{AccountName: "alice", ServerName: "New", AccountType: model.AccountTypeTOTP},
+			plaintext: "JBSWY3DPEHPK3PXP",
+		},
+		{
+			name:      "没有发行者",
+			uri:       "otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP",
+			secret:    model.Secret{AccountName: "alice", AccountType: model.AccountTypeTOTP},
+			plaintext: "JBSWY3DPEHPK3PXP",
+		},
+		{
+			name:      "HOTP",
+			uri:       "otpauth://hotp/Example:bob?secret=GEZDGNBVGY3TQOJQ&counter=42",
+			secret:    model.Secret{AccountName: "bob", ServerName: "Example", AccountType: model.AccountTypeHOTP, Counter: 42},
+			plaintext: "GEZDGNBVGY3TQOJQ",
+		},
+		{
+			name:      "HOTP 计数器为 0",
+			uri:       "otpauth://HOTP/bob?secret=GEZDGNBVGY3TQOJQ&counter=0",
+			secret:    model.Secret{AccountName: "bob", AccountType: model.AccountTypeHOTP},
+			plaintext: "GEZDGNBVGY3TQOJQ",
+		},
+		{
+			name:      "otpauth://steam",
+			uri:       "otpauth://steam/Steam:gamer?secret=JBSWY3DPEHPK3PXP",
+			secret:    model.Secret{AccountName: "gamer", ServerName: "Steam", AccountType: model.AccountTypeSteam},
+			plaintext: "SGVsbG8h3q2+7w==",
+		},
+		{
+			name:      "encoder=steam",
+			uri:       "otpauth://totp/Steam:gamer?secret=JBSWY3DPEHPK3PXP&encoder=Steam&digits=5&period=30",
+			secret:    model.Secret{AccountName: "gamer", ServerName: "Steam", AccountType: model.AccountTypeSteam, Digits: 5, Period: 30},
+			plaintext: "SGVsbG8h3q2+7w==",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account, err := parseOtpauthURI(tt.uri)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if account.secret != tt.secret {
+				t.Errorf("账户为 %+v，期望 %+v", account.secret, tt.secret)
+			}
+			if account.plaintext != tt.plaintext {
+				t.Errorf("密钥为 %q，期望 %q", account.plaintext, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestParseOtpauthURIErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		uri  string
+		want apperr.Code
+	}{
+		{"缺少 secret", "otpauth://totp/alice?issuer=GitHub", apperr.InvalidSecret},
+		{"HOTP 缺少 counter", "otpauth://hotp/bob?secret=GEZDGNBVGY3TQOJQ", apperr.InvalidArgument},
+		{"counter 不是数字", "otpauth://hotp/bob?secret=GEZDGNBVGY3TQOJQ&counter=abc", apperr.InvalidArgument},
+		{"counter 为负数", "otpauth://hotp/bob?secret=GEZDGNBVGY3TQOJQ&counter=-1", apperr.InvalidArgument},
+		{"digits 不是数字", "otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&digits=six", apperr.InvalidArgument},
+		{"period 不是数字", "otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&period=30s", apperr.InvalidArgument},
+		{"Steam 密钥不是 Base32", "otpauth://steam/gamer?secret=not-base32!", apperr.InvalidSecret},
+		{"未知类型", "otpauth://motp/alice?secret=JBSWY3DPEHPK3PXP", apperr.Unsupported},
+		{"链接格式错误", "otpauth://totp/%zz?secret=JBSWY3DPEHPK3PXP", apperr.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseOtpauthURI(tt.uri)
+			if got := apperr.CodeOf(err); err == nil || got != tt.want {
+				t.Errorf("返回 %v，期望错误码 %s", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseURI("https://example.com/?secret=JBSWY3DPEHPK3PXP"); apperr.CodeOf(err) != apperr.Unsupported {
+		t.Errorf("其他链接返回 %v", err)
+	}
+}
+
+// buildOtpauthURI 生成的链接应能被 parseOtpauthURI 还原
+func TestBuildOtpauthURIRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    model.Secret
+		plaintext string
+	}{
+		{"TOTP", model.Secret{AccountName: "alice", ServerName: "GitHub", AccountType: model.AccountTypeTOTP, Algorithm: "SHA1", Digits: 6, Period: 30}, "JBSWY3DPEHPK3PXP"},
+		{"HOTP", model.Secret{AccountName: "bob", AccountType: model.AccountTypeHOTP, Algorithm: "SHA512", Digits: 8, Counter: 7}, "GEZDGNBVGY3TQOJQ"},
+		{"Steam", model.Secret{AccountName: "gamer", ServerName: "Steam", AccountType: model.AccountTypeSteam, Algorithm: "SHA1", Digits: 5, Period: 30}, "SGVsbG8h3q2+7w=="},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri, err := buildOtpauthURI(tt.secret, tt.plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			account, err := parseOtpauthURI(uri)
+			if err != nil {
+				t.Fatalf("无法解析 %s: %v", uri, err)
+			}
+			if account.secret != tt.secret || account.plaintext != tt.plaintext {
+				t.Errorf("还原为 %+v %q，期望 %+v %q", account.secret, account.plaintext, tt.secret, tt.plaintext)
+			}
+		})
+	}
+}
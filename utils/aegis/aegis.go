@@ -0,0 +1,227 @@
+package aegis
+
+import (
+	"auth/apperr"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// 密钥槽类型，只有密码槽可以在桌面端解开
+const slotTypePassword = 1
+
+// Aegis 的密码槽固定使用 N=2^15、r=8、p=1，scrypt 约占用 32 MiB 内存，超过这些值的密码槽不是 Aegis 生成的
+const (
+	scryptMaxN = 1 << 15
+	scryptMaxR = 8
+	scryptMaxP = 1
+)
+
+// errSlotParams 密码槽的 scrypt 参数超出 Aegis 使用的范围
+var errSlotParams = apperr.New(apperr.Unsupported, "Aegis 导出文件的密钥派生参数超出范围")
+
+// ErrPasswordRequired 加密的导出文件需要提供密码
+var ErrPasswordRequired = apperr.New(apperr.PasswordRequired, "该 Aegis 导出文件已加密，请输入密码")
+
+// ErrWrongPassword 密码无法解开任何一个密钥槽
+var ErrWrongPassword = apperr.New(apperr.WrongPassword, "Aegis 导出文件的密码错误")
+
+// Entry 从 Aegis 导出文件中解析出的账户，Secret 保持 Aegis 的 Base32 编码
+type Entry struct {
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Issuer    string `json:"issuer"`
+	Secret    string `json:"secret"`
+	Algorithm string `json:"algorithm"`
+	Digits    int    `json:"digits"`
+	Period    int    `json:"period"`
+	Counter   uint64 `json:"counter"`
+}
+
+// vaultFile 导出文件的最外层结构，db 在明文导出时是对象，加密导出时是 Base64 字符串
+type vaultFile struct {
+	Version int             `json:"version"`
+	Header  header          `json:"header"`
+	DB      json.RawMessage `json:"db"`
+}
+
+type header struct {
+	Slots  []slot     `json:"slots"`
+	Params *keyParams `json:"params"`
+}
+
+type slot struct {
+	Type      int       `json:"type"`
+	Key       string    `json:"key"`
+	KeyParams keyParams `json:"key_params"`
+	N         int       `json:"n"`
+	R         int       `json:"r"`
+	P         int       `json:"p"`
+	Salt      string    `json:"salt"`
+}
+
+type keyParams struct {
+	Nonce string `json:"nonce"`
+	Tag   string `json:"tag"`
+}
+
+type database struct {
+	Version int       `json:"version"`
+	Entries []dbEntry `json:"entries"`
+}
+
+type dbEntry struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Issuer string `json:"issuer"`
+	Info   struct {
+		Secret  string `json:"secret"`
+		Algo    string `json:"algo"`
+		Digits  int    `json:"digits"`
+		Period  int    `json:"period"`
+		Counter uint64 `json:"counter"`
+	} `json:"info"`
+}
+
+// Parse 解析 Aegis 导出文件，明文导出时忽略 password
+func Parse(data []byte, password string) ([]Entry, error) {
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, apperr.Wrap(apperr.Unsupported, "Aegis 导出文件格式错误", err)
+	}
+	if file.Version != 1 {
+		return nil, apperr.New(apperr.Unsupported, fmt.Sprintf("不支持的 Aegis 导出文件版本: %d", file.Version))
+	}
+
+	dbJSON := []byte(file.DB)
+	if file.Header.Params != nil {
+		if password == "" {
+			return nil, ErrPasswordRequired
+		}
+
+		var encoded string
+		if err := json.Unmarshal(file.DB, &encoded); err != nil {
+			return nil, apperr.Wrap(apperr.Unsupported, "Aegis 导出文件格式错误", err)
+		}
+
+		var err error
+		dbJSON, err = decryptDatabase(file.Header, encoded, password)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var db database
+	if err := json.Unmarshal(dbJSON, &db); err != nil {
+		return nil, apperr.Wrap(apperr.Unsupported, "Aegis 数据库格式错误", err)
+	}
+
+	entries := make([]Entry, 0, len(db.Entries))
+	for _, e := range db.Entries {
+		entries = append(entries, Entry{
+			Type:      strings.ToLower(e.Type),
+			Name:      e.Name,
+			Issuer:    e.Issuer,
+			Secret:    e.Info.Secret,
+			Algorithm: strings.ToUpper(e.Info.Algo),
+			Digits:    e.Info.Digits,
+			Period:    e.Info.Period,
+			Counter:   e.Info.Counter,
+		})
+	}
+
+	return entries, nil
+}
+
+// decryptDatabase 依次尝试密码槽解出主密钥，再用主密钥解密数据库
+func decryptDatabase(h header, encoded string, password string) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("Aegis 数据库 Base64 解码失败: %w", err)
+	}
+
+	var rejected error
+	for _, s := range h.Slots {
+		if s.Type != slotTypePassword {
+			continue
+		}
+
+		masterKey, err := openSlot(s, password)
+		if err == errSlotParams {
+			rejected = err
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		dbJSON, err := openGCM(masterKey, *h.Params, ciphertext)
+		if err != nil {
+			// 主密钥已经解开，说明密码正确，是数据库本身损坏
+			return nil, apperr.Wrap(apperr.Unsupported, "Aegis 数据库解密失败，导出文件可能已损坏", err)
+		}
+		return dbJSON, nil
+	}
+
+	if rejected != nil {
+		return nil, rejected
+	}
+	return nil, ErrWrongPassword
+}
+
+// openSlot 用 scrypt 从密码派生密钥，解开密钥槽中的主密钥
+func openSlot(s slot, password string) ([]byte, error) {
+	if s.N < 2 || s.N > scryptMaxN || s.R < 1 || s.R > scryptMaxR || s.P < 1 || s.P > scryptMaxP {
+		return nil, errSlotParams
+	}
+
+	salt, err := hex.DecodeString(s.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := scrypt.Key([]byte(password), salt, s.N, s.R, s.P, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedKey, err := hex.DecodeString(s.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return openGCM(key, s.KeyParams, encryptedKey)
+}
+
+// openGCM Aegis 把 nonce 和 tag 单独存放，解密前需要把 tag 拼回密文末尾
+func openGCM(key []byte, params keyParams, ciphertext []byte) ([]byte, error) {
+	nonce, err := hex.DecodeString(params.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := hex.DecodeString(params.Tag)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aesgcm, err := cipher.NewGCMWithNonceSize(block, len(nonce))
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := make([]byte, 0, len(ciphertext)+len(tag))
+	sealed = append(sealed, ciphertext...)
+	sealed = append(sealed, tag...)
+
+	return aesgcm.Open(nil, nonce, sealed, nil)
+}
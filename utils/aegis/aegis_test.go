@@ -0,0 +1,105 @@
+package aegis
+
+import (
+	"auth/apperr"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+// testdata 中的两个文件内容相同，encrypted.json 的密码为 test，另有一个应被跳过的生物识别槽
+var wantEntries = []Entry{
+	{Type: "totp", Name: "alice@example.com", Issuer: "GitHub", Secret: "JBSWY3DPEHPK3PXP", Algorithm: "SHA1", Digits: 6, Period: 30},
+	{Type: "hotp", Name: "bob", Issuer: "Example", Secret: "GEZDGNBVGY3TQOJQ", Algorithm: "SHA256", Digits: 8, Counter: 42},
+	{Type: "steam", Name: "gamer", Issuer: "Steam", Secret: "KRSXG5CTMVRXEZLU", Algorithm: "SHA1", Digits: 5, Period: 30},
+	{Type: "motp", Name: "legacy", Issuer: "Bank", Secret: "MZXW6YTBOI", Algorithm: "MD5", Digits: 6, Period: 10},
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func checkEntries(t *testing.T, entries []Entry) {
+	t.Helper()
+	if len(entries) != len(wantEntries) {
+		t.Fatalf("解析出 %d 个账户，期望 %d 个", len(entries), len(wantEntries))
+	}
+	for i, want := range wantEntries {
+		if entries[i] != want {
+			t.Errorf("第 %d 个账户为 %+v，期望 %+v", i, entries[i], want)
+		}
+	}
+}
+
+func TestParsePlain(t *testing.T) {
+	entries, err := Parse(readFixture(t, "plain.json"), "ignored")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEntries(t, entries)
+}
+
+func TestParseEncrypted(t *testing.T) {
+	data := readFixture(t, "encrypted.json")
+
+	entries, err := Parse(data, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkEntries(t, entries)
+
+	if _, err := Parse(data, ""); !errors.Is(err, ErrPasswordRequired) {
+		t.Errorf("没有密码时返回 %v", err)
+	}
+	if _, err := Parse(data, "wrong"); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("密码错误时返回 %v", err)
+	}
+}
+
+// modifyFile 解析导出文件，修改后重新编码
+func modifyFile(t *testing.T, data []byte, modify func(f *vaultFile)) []byte {
+	t.Helper()
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	modify(&file)
+	out, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestParseRejectsInvalidFile(t *testing.T) {
+	encrypted := readFixture(t, "encrypted.json")
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"不是 JSON", []byte("not json"), apperr.New(apperr.Unsupported, "")},
+		{"不支持的版本", modifyFile(t, readFixture(t, "plain.json"), func(f *vaultFile) { f.Version = 2 }), apperr.New(apperr.Unsupported, "")},
+		{"N 过大", modifyFile(t, encrypted, func(f *vaultFile) { f.Header.Slots[1].N = scryptMaxN * 2 }), errSlotParams},
+		{"r 过大", modifyFile(t, encrypted, func(f *vaultFile) { f.Header.Slots[1].R = scryptMaxR + 1 }), errSlotParams},
+		{"p 过大", modifyFile(t, encrypted, func(f *vaultFile) { f.Header.Slots[1].P = scryptMaxP + 1 }), errSlotParams},
+		{"N 过小", modifyFile(t, encrypted, func(f *vaultFile) { f.Header.Slots[1].N = 1 }), errSlotParams},
+		{"没有密码槽", modifyFile(t, encrypted, func(f *vaultFile) { f.Header.Slots = f.Header.Slots[:1] }), ErrWrongPassword},
+		{"数据库被篡改", modifyFile(t, encrypted, func(f *vaultFile) { f.Header.Params.Tag = f.Header.Slots[1].KeyParams.Tag }), apperr.New(apperr.Unsupported, "")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.data, "test"); !errors.Is(err, tt.want) {
+				t.Errorf("返回 %v，期望错误码 %s", err, apperr.CodeOf(tt.want))
+			}
+		})
+	}
+}
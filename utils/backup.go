@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"auth/apperr"
+	"auth/model"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// 备份文件格式标识和版本
+const (
+	BackupFormat  = "euthenticator-backup"
+	BackupVersion = 1
+)
+
+// 恢复备份时接受的 Argon2id 参数范围。本程序生成的备份使用 NewKDFParams 的参数，
+// 上限为以后调高参数留出余量，其中内存最多 1 GiB，盐的长度与 argon2SaltLen 相同或更长
+const (
+	backupMaxTime    = 10
+	backupMaxMemory  = 1024 * 1024 // 单位 KiB，即 1 GiB
+	backupMaxThreads = 16
+	backupMinSaltLen = 16
+	backupMaxSaltLen = 64
+)
+
+// ErrWrongBackupPassword 备份密码错误或文件已被篡改
+var ErrWrongBackupPassword = apperr.New(apperr.WrongPassword, "备份密码错误或备份文件已损坏")
+
+// BackupFile 备份文件结构，除 Ciphertext 外的字段都作为附加数据参与认证
+type BackupFile struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	KDF        BackupKDF `json:"kdf"`
+	Nonce      []byte    `json:"nonce"`
+	Ciphertext []byte    `json:"ciphertext,omitempty"`
+}
+
+// BackupKDF 备份密码的派生参数
+type BackupKDF struct {
+	Name    string `json:"name"`
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// BackupPayload 加密前的备份内容
+type BackupPayload struct {
+	CreatedAt time.Time     `json:"created_at"`
+	Secrets   []BackupEntry `json:"secrets"`
+}
+
+// BackupEntry 一个账户的完整信息，密钥为明文
+type BackupEntry struct {
+	AccountName string `json:"account_name"`
+	ServerName  string `json:"server_name"`
+	AccountType uint   `json:"account_type"`
+	Secret      string `json:"secret"`
+	Algorithm   string `json:"algorithm"`
+	Digits      uint   `json:"digits"`
+	Period      uint   `json:"period"`
+	Counter     uint64 `json:"counter"`
+}
+
+// SealBackup 用备份密码加密备份内容，返回可直接写入文件的数据
+func SealBackup(payload BackupPayload, password string) ([]byte, error) {
+	params, err := NewKDFParams()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := DeriveKey(password, params)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	file := BackupFile{
+		Format:  BackupFormat,
+		Version: BackupVersion,
+		KDF: BackupKDF{
+			Name:    "argon2id",
+			Salt:    params.Salt,
+			Time:    params.Time,
+			Memory:  params.Memory,
+			Threads: params.Threads,
+		},
+		Nonce: nonce,
+	}
+
+	additionalData, err := json.Marshal(file)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	file.Ciphertext = aead.Seal(nil, nonce, plaintext, additionalData)
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// OpenBackup 校验并解密备份文件
+func OpenBackup(data []byte, password string) (BackupPayload, error) {
+	var file BackupFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return BackupPayload{}, apperr.Wrap(apperr.Unsupported, "备份文件格式错误", err)
+	}
+
+	if file.Format != BackupFormat {
+		return BackupPayload{}, apperr.New(apperr.Unsupported, "不是有效的备份文件")
+	}
+	if file.Version > BackupVersion {
+		return BackupPayload{}, apperr.New(apperr.Unsupported, fmt.Sprintf("备份文件版本 %d 高于程序支持的版本 %d", file.Version, BackupVersion))
+	}
+	if file.KDF.Name != "argon2id" {
+		return BackupPayload{}, apperr.New(apperr.Unsupported, "不支持的密钥派生算法: "+file.KDF.Name)
+	}
+	if err := checkBackupKDF(file.KDF); err != nil {
+		return BackupPayload{}, err
+	}
+
+	key, err := DeriveKey(password, model.KDFParams{
+		Version: model.KDFVersionArgon2id,
+		Salt:    file.KDF.Salt,
+		Time:    file.KDF.Time,
+		Memory:  file.KDF.Memory,
+		Threads: file.KDF.Threads,
+	})
+	if err != nil {
+		return BackupPayload{}, err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return BackupPayload{}, err
+	}
+	if len(file.Nonce) != aead.NonceSize() {
+		return BackupPayload{}, apperr.New(apperr.Unsupported, "备份文件格式错误: nonce 长度不正确")
+	}
+
+	ciphertext := file.Ciphertext
+	file.Ciphertext = nil
+	additionalData, err := json.Marshal(file)
+	if err != nil {
+		return BackupPayload{}, err
+	}
+
+	plaintext, err := aead.Open(nil, file.Nonce, ciphertext, additionalData)
+	if err != nil {
+		return BackupPayload{}, ErrWrongBackupPassword
+	}
+
+	var payload BackupPayload
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		return BackupPayload{}, apperr.Wrap(apperr.Unsupported, "备份内容格式错误", err)
+	}
+
+	return payload, nil
+}
+
+// checkBackupKDF 在派生密钥前检查备份文件中的参数是否在合理范围内
+func checkBackupKDF(kdf BackupKDF) error {
+	switch {
+	case len(kdf.Salt) < backupMinSaltLen || len(kdf.Salt) > backupMaxSaltLen:
+		return apperr.New(apperr.Unsupported, fmt.Sprintf("备份文件的盐长度应为 %d 到 %d 字节，实际为 %d 字节", backupMinSaltLen, backupMaxSaltLen, len(kdf.Salt)))
+	case kdf.Time == 0 || kdf.Time > backupMaxTime:
+		return apperr.New(apperr.Unsupported, fmt.Sprintf("备份文件的迭代次数 %d 超出范围", kdf.Time))
+	case kdf.Memory == 0 || kdf.Memory > backupMaxMemory:
+		return apperr.New(apperr.Unsupported, fmt.Sprintf("备份文件的内存参数 %d KiB 超出范围", kdf.Memory))
+	case kdf.Threads == 0 || kdf.Threads > backupMaxThreads:
+		return apperr.New(apperr.Unsupported, fmt.Sprintf("备份文件的线程数 %d 超出范围", kdf.Threads))
+	}
+	return nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
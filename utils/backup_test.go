@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"auth/apperr"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func testBackupPayload() BackupPayload {
+	return BackupPayload{
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Secrets: []BackupEntry{
+			{AccountName: "alice", ServerName: "GitHub", Secret: "JBSWY3DPEHPK3PXP", Algorithm: "SHA1", Digits: 6, Period: 30},
+			{AccountName: "bob", ServerName: "Example", AccountType: 2, Secret: "GEZDGNBVGY3TQOJQ", Algorithm: "SHA256", Digits: 8, Counter: 7},
+		},
+	}
+}
+
+// modifyBackup 解析备份文件，修改后重新编码
+func modifyBackup(t *testing.T, data []byte, modify func(f *BackupFile)) []byte {
+	t.Helper()
+	var file BackupFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	modify(&file)
+	out, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	data, err := SealBackup(testBackupPayload(), "backup-pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := OpenBackup(data, "backup-pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testBackupPayload()
+	if !payload.CreatedAt.Equal(want.CreatedAt) || len(payload.Secrets) != len(want.Secrets) {
+		t.Fatalf("恢复的内容为 %+v", payload)
+	}
+	for i := range want.Secrets {
+		if payload.Secrets[i] != want.Secrets[i] {
+			t.Errorf("第 %d 个账户为 %+v，期望 %+v", i, payload.Secrets[i], want.Secrets[i])
+		}
+	}
+
+	if _, err := OpenBackup(data, "wrong"); !errors.Is(err, ErrWrongBackupPassword) {
+		t.Errorf("备份密码错误时返回 %v", err)
+	}
+}
+
+// 头信息作为附加数据参与认证，修改后即使派生参数仍然合法也无法解密
+func TestOpenBackupRejectsTamperedHeader(t *testing.T) {
+	data, err := SealBackup(testBackupPayload(), "backup-pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(f *BackupFile)
+	}{
+		{"版本", func(f *BackupFile) { f.Version = 0 }},
+		{"迭代次数", func(f *BackupFile) { f.KDF.Time++ }},
+		{"盐", func(f *BackupFile) { f.KDF.Salt[0] ^= 1 }},
+		{"nonce", func(f *BackupFile) { f.Nonce[0] ^= 1 }},
+		{"密文", func(f *BackupFile) { f.Ciphertext[0] ^= 1 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := OpenBackup(modifyBackup(t, data, tt.modify), "backup-pw")
+			if !errors.Is(err, ErrWrongBackupPassword) {
+				t.Errorf("返回 %v，期望 %v", err, ErrWrongBackupPassword)
+			}
+		})
+	}
+}
+
+// 格式错误和超出范围的派生参数在派生密钥之前就被拒绝
+func TestOpenBackupRejectsInvalidHeader(t *testing.T) {
+	valid := BackupFile{
+		Format:  BackupFormat,
+		Version: BackupVersion,
+		KDF:     BackupKDF{Name: "argon2id", Salt: make([]byte, 16), Time: 1, Memory: 64, Threads: 1},
+		Nonce:   make([]byte, 12),
+	}
+	data, err := json.Marshal(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(f *BackupFile)
+	}{
+		{"格式标识", func(f *BackupFile) { f.Format = "aegis" }},
+		{"更高的版本", func(f *BackupFile) { f.Version = BackupVersion + 1 }},
+		{"派生算法", func(f *BackupFile) { f.KDF.Name = "scrypt" }},
+		{"盐过短", func(f *BackupFile) { f.KDF.Salt = make([]byte, backupMinSaltLen-1) }},
+		{"盐过长", func(f *BackupFile) { f.KDF.Salt = make([]byte, backupMaxSaltLen+1) }},
+		{"迭代次数为 0", func(f *BackupFile) { f.KDF.Time = 0 }},
+		{"迭代次数过大", func(f *BackupFile) { f.KDF.Time = backupMaxTime + 1 }},
+		{"内存过大", func(f *BackupFile) { f.KDF.Memory = backupMaxMemory + 1 }},
+		{"线程数为 0", func(f *BackupFile) { f.KDF.Threads = 0 }},
+		{"线程数过大", func(f *BackupFile) { f.KDF.Threads = backupMaxThreads + 1 }},
+		{"nonce 长度", func(f *BackupFile) { f.Nonce = make([]byte, 8) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := OpenBackup(modifyBackup(t, data, tt.modify), "backup-pw")
+			if code := apperr.CodeOf(err); code != apperr.Unsupported {
+				t.Errorf("错误码为 %s，期望 %s: %v", code, apperr.Unsupported, err)
+			}
+		})
+	}
+
+	t.Run("不是 JSON", func(t *testing.T) {
+		if _, err := OpenBackup([]byte("not json"), "backup-pw"); apperr.CodeOf(err) != apperr.Unsupported {
+			t.Errorf("返回 %v", err)
+		}
+	})
+}
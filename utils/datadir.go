@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// DataDirEnv 指定数据目录的环境变量
+const DataDirEnv = "EUTHENTICATOR_DATA_DIR"
+
+// appDirName 数据目录下的应用子目录名
+const appDirName = "Euthenticator"
+
+// ResolveDataDir 确定数据目录并确保其存在，优先级：命令行参数 > 环境变量 > 平台默认目录
+func ResolveDataDir(flagValue string) (string, error) {
+	dir := flagValue
+	if dir == "" {
+		dir = os.Getenv(DataDirEnv)
+	}
+	if dir == "" {
+		var err error
+		dir, err = DefaultDataDir()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// DefaultDataDir 返回平台默认的数据目录
+//   - Linux 等：$XDG_DATA_HOME/Euthenticator，未设置时为 ~/.local/share/Euthenticator
+//   - Windows：%AppData%\Euthenticator
+//   - macOS：~/Library/Application Support/Euthenticator
+func DefaultDataDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows", "darwin":
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, appDirName), nil
+	}
+
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appDirName), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	if home == "" {
+		return "", errors.New("无法确定用户主目录")
+	}
+	return filepath.Join(home, ".local", "share", appDirName), nil
+}
@@ -1,109 +1,183 @@
 package utils
 
 import (
-	"crypto/aes"
+	"auth/apperr"
+	"auth/logger"
+	"auth/model"
 	"crypto/cipher"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
-	"log"
-	"os"
-	"path/filepath"
-
-	"github.com/joho/godotenv"
+	"sync"
 )
 
-// 这个变量将在编译时通过ldflags注入
-var embeddedMasterPassword string
-
-func init() {
-	// 尝试多种方式加载环境变量:
-	// 1. 先尝试从当前目录加载
-	err1 := godotenv.Load(".env.local", ".env")
-
-	// 2. 再尝试从可执行文件所在目录加载
-	execPath, err := os.Executable()
-	if err == nil {
-		execDir := filepath.Dir(execPath)
-		err2 := godotenv.Load(filepath.Join(execDir, ".env.local"), filepath.Join(execDir, ".env"))
-		if err1 != nil && err2 != nil {
-			log.Println("Warning: Failed to load .env files from current and executable directories")
-		}
-	} else {
-		log.Println("Warning: Failed to determine executable path:", err)
-		if err1 != nil {
-			log.Println("Warning: Also failed to load .env files from current directory")
-		}
+// vaultVerifierPlaintext 用于校验主密码的已知明文
+const vaultVerifierPlaintext = "euthenticator-vault"
+
+// ErrWrongPassword 主密码错误
+var ErrWrongPassword = apperr.New(apperr.WrongPassword, "主密码错误")
+
+// ErrCipherWiped 密钥已被清除，通常是保险库在操作过程中被锁定
+var ErrCipherWiped = apperr.New(apperr.VaultLocked, "保险库已锁定，请先输入主密码解锁")
+
+// Cipher 解锁时由主密码派生的密钥创建，持有 AES-GCM 实例供所有记录复用
+// 可以并发使用，锁定时调用 Wipe 清除密钥，之后的加解密都返回 ErrCipherWiped
+type Cipher struct {
+	mu              sync.RWMutex
+	key             []byte
+	aead            cipher.AEAD
+	vaultID         string // 为空表示旧保险库，密文没有绑定到所在的行
+	encryptMetadata bool   // 账户名称和服务商是否加密保存
+}
+
+// NewCipher 用 key 创建 Cipher，key 由 Cipher 接管并在 Wipe 时清零，调用方不应再使用
+// 加密方式由保险库头信息决定：VaultID 与账户 ID、类型一起作为密文的关联数据，EncryptedMetadata 为真时同时加密账户名称和服务商
+func NewCipher(key []byte, header model.VaultHeader) (*Cipher, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
 	}
+	return &Cipher{key: key, aead: aead, vaultID: header.VaultID, encryptMetadata: header.EncryptedMetadata}, nil
 }
 
-// deriveKey 用主密码生成加密Key（内部私有）
-func deriveKey() []byte {
-	// 优先使用环境变量
-	masterPassword := os.Getenv("MASTER_PASSWORD")
+// NewVaultID 生成随机的保险库 ID
+func NewVaultID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
 
-	// 如果环境变量为空，使用编译时嵌入的密码
-	if masterPassword == "" && embeddedMasterPassword != "" {
-		masterPassword = embeddedMasterPassword
+// VaultID 返回密文绑定的保险库 ID
+func (c *Cipher) VaultID() string {
+	return c.vaultID
+}
+
+// SealAccount 加密账户的明文密钥，返回可以写入数据库的记录，secret 中的名称为明文
+// 密文绑定到 secret 的 ID 和类型，换到其他行或修改类型后无法解密；账户名称和服务商同样绑定到所在的行
+func (c *Cipher) SealAccount(secret model.Secret, plaintext string) (model.Secret, error) {
+	encryptedSecret, err := c.seal([]byte(plaintext), c.secretAD(secret))
+	if err != nil {
+		return model.Secret{}, err
 	}
+	secret.EncryptedSecret = encryptedSecret
+	return c.SealMetadata(secret)
+}
 
-	// 如果仍然为空，则报错
-	if masterPassword == "" {
-		panic("未能获取主密码，环境变量MASTER_PASSWORD未设置且编译时未嵌入密码")
+// SealMetadata 只加密账户名称和服务商，EncryptedSecret 保持不变，账户信息不加密的旧保险库原样返回
+func (c *Cipher) SealMetadata(secret model.Secret) (model.Secret, error) {
+	if !c.encryptMetadata {
+		return secret, nil
 	}
 
-	hash := sha256.Sum256([]byte(masterPassword))
-	return hash[:]
+	var err error
+	if secret.AccountName, err = c.seal([]byte(secret.AccountName), c.metadataAD(secret, "account_name")); err != nil {
+		return model.Secret{}, err
+	}
+	if secret.ServerName, err = c.seal([]byte(secret.ServerName), c.metadataAD(secret, "server_name")); err != nil {
+		return model.Secret{}, err
+	}
+	return secret, nil
 }
 
-// Encrypt 加密后返回 Base64 字符串
-func Encrypt(plaintext []byte) (string, error) {
-	key := deriveKey()
-
-	block, err := aes.NewCipher(key)
+// OpenAccount 解密从数据库读取的记录，返回名称为明文的记录和明文密钥
+// 密文被移到其他行或类型被篡改时返回错误，同时原样返回 secret，调用方可以用其中的 ID 记录日志
+func (c *Cipher) OpenAccount(secret model.Secret) (model.Secret, string, error) {
+	opened, err := c.OpenMetadata(secret)
 	if err != nil {
-		return "", err
+		return secret, "", err
 	}
 
-	aesgcm, err := cipher.NewGCM(block)
+	plaintext, err := c.open(secret.EncryptedSecret, c.secretAD(secret))
 	if err != nil {
-		return "", err
+		return secret, "", err
+	}
+	return opened, plaintext, nil
+}
+
+// OpenMetadata 只解密账户名称和服务商，用于密钥无法解密但仍需显示名称的记录
+func (c *Cipher) OpenMetadata(secret model.Secret) (model.Secret, error) {
+	if !c.encryptMetadata {
+		return secret, nil
+	}
+
+	opened := secret
+	var err error
+	if opened.AccountName, err = c.open(secret.AccountName, c.metadataAD(secret, "account_name")); err != nil {
+		return secret, err
+	}
+	if opened.ServerName, err = c.open(secret.ServerName, c.metadataAD(secret, "server_name")); err != nil {
+		return secret, err
+	}
+	return opened, nil
+}
+
+// secretAD 返回账户密文的关联数据，旧保险库没有 ID，不使用关联数据
+func (c *Cipher) secretAD(secret model.Secret) []byte {
+	if c.vaultID == "" {
+		return nil
+	}
+	return []byte(fmt.Sprintf("euthenticator/secret/v1|%s|%d|%d", c.vaultID, secret.ID, secret.AccountType))
+}
+
+// metadataAD 返回账户名称等字段的关联数据，字段名参与其中，避免账户名称和服务商互换
+func (c *Cipher) metadataAD(secret model.Secret, column string) []byte {
+	return []byte(fmt.Sprintf("euthenticator/%s/v1|%s|%d", column, c.vaultID, secret.ID))
+}
+
+// Wipe 清零密钥并丢弃 AES-GCM 实例，可以重复调用
+// 标准库中展开后的轮密钥无法清零，只能在丢弃引用后由垃圾回收释放
+func (c *Cipher) Wipe() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.key {
+		c.key[i] = 0
+	}
+	c.key = nil
+	c.aead = nil
+}
+
+// seal 加密后返回 Base64 字符串
+func (c *Cipher) seal(plaintext []byte, additionalData []byte) (string, error) {
+	logger.Protect(string(plaintext))
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.aead == nil {
+		return "", ErrCipherWiped
 	}
 
-	nonce := make([]byte, aesgcm.NonceSize())
+	nonce := make([]byte, c.aead.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", err
 	}
 
-	ciphertext := aesgcm.Seal(nonce, nonce, plaintext, nil)
+	ciphertext := c.aead.Seal(nonce, nonce, plaintext, additionalData)
 
 	// 转成 base64 字符串，存储在数据库中
 	encoded := base64.StdEncoding.EncodeToString(ciphertext)
 	return encoded, nil
 }
 
-// Decrypt 解密 Base64 字符串
-func Decrypt(ciphertextBase64 string) (string, error) {
-	key := deriveKey()
-
+// open 解密 Base64 字符串
+func (c *Cipher) open(ciphertextBase64 string, additionalData []byte) (string, error) {
 	ciphertext, err := base64.StdEncoding.DecodeString(ciphertextBase64)
 	if err != nil {
 		return "", err
 	}
 
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", err
-	}
-
-	aesgcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.aead == nil {
+		return "", ErrCipherWiped
 	}
 
-	nonceSize := aesgcm.NonceSize()
+	nonceSize := c.aead.NonceSize()
 	if len(ciphertext) < nonceSize {
 		return "", errors.New("密文数据太短，无法解密")
 	}
@@ -111,10 +185,25 @@ func Decrypt(ciphertextBase64 string) (string, error) {
 	nonce := ciphertext[:nonceSize]
 	ciphertext = ciphertext[nonceSize:]
 
-	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, additionalData)
 	if err != nil {
 		return "", err
 	}
 
+	logger.Protect(string(plaintext))
 	return string(plaintext), nil
 }
+
+// NewVerifier 加密已知明文，保存后可用于校验主密码
+func (c *Cipher) NewVerifier() (string, error) {
+	return c.seal([]byte(vaultVerifierPlaintext), nil)
+}
+
+// CheckVerifier 校验能否解开 NewVerifier 生成的校验值
+func (c *Cipher) CheckVerifier(verifier string) error {
+	plaintext, err := c.open(verifier, nil)
+	if err != nil || plaintext != vaultVerifierPlaintext {
+		return ErrWrongPassword
+	}
+	return nil
+}
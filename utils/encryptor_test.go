@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"auth/model"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// testKDF 测试用的 Argon2id 参数，成本远低于正式参数
+var testKDF = model.KDFParams{
+	Version: model.KDFVersionArgon2id,
+	Salt:    bytes.Repeat([]byte{1}, argon2SaltLen),
+	Time:    1,
+	Memory:  64,
+	Threads: 1,
+}
+
+func newTestCipher(t *testing.T, password string, vaultID string) *Cipher {
+	t.Helper()
+	c, err := DeriveCipher(password, model.VaultHeader{KDF: testKDF, VaultID: vaultID, EncryptedMetadata: vaultID != ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func testSecret() model.Secret {
+	return model.Secret{ID: 3, AccountName: "alice", ServerName: "GitHub", AccountType: model.AccountTypeTOTP}
+}
+
+func TestSealOpenAccount(t *testing.T) {
+	c := newTestCipher(t, "pw", "vault-a")
+
+	sealed, err := c.SealAccount(testSecret(), "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealed.AccountName == "alice" || sealed.ServerName == "GitHub" {
+		t.Errorf("账户名称或服务商没有加密: %+v", sealed)
+	}
+
+	opened, plaintext, err := c.OpenAccount(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("密钥为 %q", plaintext)
+	}
+	if opened.AccountName != "alice" || opened.ServerName != "GitHub" {
+		t.Errorf("解密后的名称为 %q/%q", opened.ServerName, opened.AccountName)
+	}
+}
+
+func TestOpenAccountRejectsMovedCiphertext(t *testing.T) {
+	c := newTestCipher(t, "pw", "vault-a")
+	sealed, err := c.SealAccount(testSecret(), "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		cipher *Cipher
+		modify func(s *model.Secret)
+	}{
+		{"其他行", c, func(s *model.Secret) { s.ID = 4 }},
+		{"其他类型", c, func(s *model.Secret) { s.AccountType = model.AccountTypeHOTP }},
+		{"账户名称和服务商互换", c, func(s *model.Secret) { s.AccountName, s.ServerName = s.ServerName, s.AccountName }},
+		{"其他保险库", newTestCipher(t, "pw", "vault-b"), func(s *model.Secret) {}},
+		{"其他主密码", newTestCipher(t, "other", "vault-a"), func(s *model.Secret) {}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			moved := sealed
+			tt.modify(&moved)
+			if _, _, err := tt.cipher.OpenAccount(moved); err == nil {
+				t.Error("密文换到其他位置后仍能解密")
+			}
+		})
+	}
+
+	t.Run("只移动密钥", func(t *testing.T) {
+		other, err := c.SealAccount(model.Secret{ID: 4, AccountName: "bob", ServerName: "GitLab"}, "GEZDGNBVGY3TQOJQ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		other.EncryptedSecret = sealed.EncryptedSecret
+		if _, _, err := c.OpenAccount(other); err == nil {
+			t.Error("密钥换到其他行后仍能解密")
+		}
+		if _, err := c.OpenMetadata(other); err != nil {
+			t.Errorf("名称没有移动，应能单独解密: %v", err)
+		}
+	})
+}
+
+// 旧保险库的密文没有绑定到所在的行，名称也是明文
+func TestLegacyCipherWithoutVaultID(t *testing.T) {
+	c := newTestCipher(t, "pw", "")
+	sealed, err := c.SealAccount(testSecret(), "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealed.AccountName != "alice" {
+		t.Errorf("旧保险库不应加密账户名称: %q", sealed.AccountName)
+	}
+
+	sealed.ID = 4
+	if _, plaintext, err := c.OpenAccount(sealed); err != nil || plaintext != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("OpenAccount = %q, %v", plaintext, err)
+	}
+}
+
+func TestWipe(t *testing.T) {
+	c := newTestCipher(t, "pw", "vault-a")
+	sealed, err := c.SealAccount(testSecret(), "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Wipe()
+	c.Wipe()
+	if _, err := c.SealAccount(testSecret(), "JBSWY3DPEHPK3PXP"); !errors.Is(err, ErrCipherWiped) {
+		t.Errorf("清除后加密返回 %v", err)
+	}
+	if _, _, err := c.OpenAccount(sealed); !errors.Is(err, ErrCipherWiped) {
+		t.Errorf("清除后解密返回 %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"auth/logger"
+	"auth/model"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// 新建保险库时使用的 Argon2id 参数
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	argon2SaltLen = 16
+	keyLen        = 32
+)
+
+// legacyMasterPassword 旧版本发布包在编译时通过 ldflags 注入的主密码，用户并不知道它
+// 只用于把这些保险库迁移到用户自己设置的主密码，过渡版本之后删除
+var legacyMasterPassword string
+
+// LegacyCipher 按旧版本的方式取得内置主密码并派生 Cipher：优先使用环境变量 MASTER_PASSWORD，其次使用编译时注入的密码
+// 两者都没有时返回 nil
+func LegacyCipher() (*Cipher, error) {
+	password := os.Getenv("MASTER_PASSWORD")
+	if password == "" {
+		password = legacyMasterPassword
+	}
+	if password == "" {
+		return nil, nil
+	}
+
+	logger.Protect(password)
+	return DeriveCipher(password, model.VaultHeader{KDF: model.KDFParams{Version: model.KDFVersionSHA256}})
+}
+
+// NewKDFParams 生成使用当前算法和随机盐的派生参数
+func NewKDFParams() (model.KDFParams, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return model.KDFParams{}, err
+	}
+
+	return model.KDFParams{
+		Version: model.KDFVersionArgon2id,
+		Salt:    salt,
+		Time:    argon2Time,
+		Memory:  argon2Memory,
+		Threads: argon2Threads,
+	}, nil
+}
+
+// DeriveKey 按派生参数用主密码生成加密Key
+func DeriveKey(masterPassword string, params model.KDFParams) ([]byte, error) {
+	switch params.Version {
+	case model.KDFVersionSHA256:
+		hash := sha256.Sum256([]byte(masterPassword))
+		return hash[:], nil
+	case model.KDFVersionArgon2id:
+		if len(params.Salt) == 0 || params.Time == 0 || params.Memory == 0 || params.Threads == 0 {
+			return nil, fmt.Errorf("Argon2id 参数不完整")
+		}
+		return argon2.IDKey([]byte(masterPassword), params.Salt, params.Time, params.Memory, params.Threads, keyLen), nil
+	default:
+		return nil, fmt.Errorf("不支持的密钥派生版本: %d", params.Version)
+	}
+}
+
+// DeriveCipher 按头信息中的派生参数用主密码生成密钥，并创建按该保险库的方式加解密的 Cipher
+func DeriveCipher(masterPassword string, header model.VaultHeader) (*Cipher, error) {
+	key, err := DeriveKey(masterPassword, header.KDF)
+	if err != nil {
+		return nil, err
+	}
+	return NewCipher(key, header)
+}
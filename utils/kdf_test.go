@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"auth/model"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestDeriveKey(t *testing.T) {
+	key, err := DeriveKey("pw", testKDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != keyLen {
+		t.Fatalf("密钥长度为 %d", len(key))
+	}
+
+	again, err := DeriveKey("pw", testKDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, again) {
+		t.Error("相同的密码和参数派生出不同的密钥")
+	}
+
+	otherSalt := testKDF
+	otherSalt.Salt = bytes.Repeat([]byte{2}, argon2SaltLen)
+	for name, derive := range map[string]func() ([]byte, error){
+		"其他密码": func() ([]byte, error) { return DeriveKey("other", testKDF) },
+		"其他盐":  func() ([]byte, error) { return DeriveKey("pw", otherSalt) },
+	} {
+		other, err := derive()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(key, other) {
+			t.Errorf("%s派生出相同的密钥", name)
+		}
+	}
+}
+
+func TestDeriveKeySHA256(t *testing.T) {
+	key, err := DeriveKey("pw", model.KDFParams{Version: model.KDFVersionSHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sha256.Sum256([]byte("pw"))
+	if !bytes.Equal(key, want[:]) {
+		t.Error("旧版本的密钥应为主密码的 SHA-256")
+	}
+}
+
+func TestDeriveKeyInvalidParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(p *model.KDFParams)
+	}{
+		{"未知版本", func(p *model.KDFParams) { p.Version = 99 }},
+		{"没有盐", func(p *model.KDFParams) { p.Salt = nil }},
+		{"迭代次数为 0", func(p *model.KDFParams) { p.Time = 0 }},
+		{"内存为 0", func(p *model.KDFParams) { p.Memory = 0 }},
+		{"线程数为 0", func(p *model.KDFParams) { p.Threads = 0 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := testKDF
+			tt.modify(&params)
+			if _, err := DeriveKey("pw", params); err == nil {
+				t.Error("参数无效时应返回错误")
+			}
+		})
+	}
+}
+
+func TestNewKDFParams(t *testing.T) {
+	a, err := NewKDFParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewKDFParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Version != model.KDFVersionArgon2id || a.Time != argon2Time || a.Memory != argon2Memory || a.Threads != argon2Threads {
+		t.Errorf("派生参数为 %+v", a)
+	}
+	if len(a.Salt) != argon2SaltLen {
+		t.Errorf("盐长度为 %d", len(a.Salt))
+	}
+	if bytes.Equal(a.Salt, b.Salt) {
+		t.Error("两次生成的盐相同")
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	c := newTestCipher(t, "pw", "vault-a")
+	verifier, err := c.NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := newTestCipher(t, "pw", "vault-a").CheckVerifier(verifier); err != nil {
+		t.Errorf("正确的主密码校验失败: %v", err)
+	}
+	if err := newTestCipher(t, "other", "vault-a").CheckVerifier(verifier); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("错误的主密码返回 %v", err)
+	}
+	if err := c.CheckVerifier("not base64!"); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("损坏的校验值返回 %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/pquerna/otp"
+)
+
+// ParseAlgorithm 将算法名称转换为 otp.Algorithm，名称为空时使用 SHA1
+func ParseAlgorithm(name string) (otp.Algorithm, error) {
+	switch strings.ToUpper(name) {
+	case "", "SHA1":
+		return otp.AlgorithmSHA1, nil
+	case "SHA256":
+		return otp.AlgorithmSHA256, nil
+	case "SHA512":
+		return otp.AlgorithmSHA512, nil
+	case "MD5":
+		return otp.AlgorithmMD5, nil
+	default:
+		return otp.AlgorithmSHA1, fmt.Errorf("不支持的算法: %s", name)
+	}
+}
+
+// SteamSecretFromBase32 把 Base32 编码的 Steam 密钥转换为 Steam Guard 使用的 Base64 编码
+// Aegis 和 otpauth://steam 链接都以 Base32 保存 Steam 的 shared_secret
+func SteamSecretFromBase32(secret string) (string, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	raw, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+	if err != nil {
+		return "", fmt.Errorf("Steam 密钥 Base32 解码失败: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// SteamSecretToBase32 把 Steam Guard 使用的 Base64 密钥转换为 otpauth 链接中的 Base32 编码
+func SteamSecretToBase32(secret string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return "", fmt.Errorf("Steam 密钥 Base64 解码失败: %w", err)
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw), nil
+}
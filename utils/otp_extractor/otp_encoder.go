@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+
+	pb "auth/utils/otp_extractor/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// 谷歌验证器每个导出二维码最多包含 10 个账户
+const migrationMaxEntries = 10
+
+// 单个二维码中导出链接的最大长度，超过后二维码过于密集，手机难以识别
+const migrationMaxURLLength = 1200
+
+// BuildMigrationURLs 把账户编码为谷歌验证器导出链接，账户较多时拆分为同一批次的多个链接
+func BuildMigrationURLs(entries []OtpEntry) ([]string, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("没有需要导出的账户")
+	}
+
+	params := make([]*pb.MigrationPayload_OtpParameters, 0, len(entries))
+	for _, entry := range entries {
+		p, err := migrationParameters(entry)
+		if err != nil {
+			return nil, fmt.Errorf("账户 %s 无法导出: %w", entry.Name, err)
+		}
+		params = append(params, p)
+	}
+
+	batchID, err := randomBatchID()
+	if err != nil {
+		return nil, err
+	}
+
+	// 按数量和链接长度分组，测量时带上批次字段：batch_id 最长占 6 字节，
+	// batch_index 为 0 时不编码，之后的链接会多出这两个字节，因此按该链接实际的序号测量
+	var batches [][]*pb.MigrationPayload_OtpParameters
+	var current []*pb.MigrationPayload_OtpParameters
+	for _, p := range params {
+		candidate := append(current[:len(current):len(current)], p)
+		u, err := encodeMigrationURL(&pb.MigrationPayload{
+			OtpParameters: candidate,
+			Version:       1,
+			BatchSize:     int32(len(batches) + 1),
+			BatchIndex:    int32(len(batches)),
+			BatchId:       batchID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(current) > 0 && (len(candidate) > migrationMaxEntries || len(u) > migrationMaxURLLength) {
+			batches = append(batches, current)
+			current = []*pb.MigrationPayload_OtpParameters{p}
+			continue
+		}
+		current = candidate
+	}
+	batches = append(batches, current)
+
+	urls := make([]string, 0, len(batches))
+	for i, batch := range batches {
+		u, err := encodeMigrationURL(&pb.MigrationPayload{
+			OtpParameters: batch,
+			Version:       1,
+			BatchSize:     int32(len(batches)),
+			BatchIndex:    int32(i),
+			BatchId:       batchID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+
+	return urls, nil
+}
+
+// migrationParameters 把账户转换为导出格式，谷歌验证器不支持的参数会返回错误
+func migrationParameters(entry OtpEntry) (*pb.MigrationPayload_OtpParameters, error) {
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).
+		DecodeString(strings.TrimRight(strings.ToUpper(strings.ReplaceAll(entry.Secret, " ", "")), "="))
+	if err != nil {
+		return nil, fmt.Errorf("密钥 Base32 解码失败: %v", err)
+	}
+
+	p := &pb.MigrationPayload_OtpParameters{
+		Secret: secret,
+		Name:   entry.Name,
+		Issuer: entry.Issuer,
+	}
+
+	switch entry.Type {
+	case "totp":
+		if entry.Period != 0 && entry.Period != migrationPeriod {
+			return nil, fmt.Errorf("谷歌验证器只支持 %d 秒周期", migrationPeriod)
+		}
+		p.Type = pb.MigrationPayload_OTP_TOTP
+	case "hotp":
+		p.Type = pb.MigrationPayload_OTP_HOTP
+		p.Counter = entry.Counter
+	default:
+		return nil, fmt.Errorf("谷歌验证器不支持 %s 类型", entry.Type)
+	}
+
+	algorithm := strings.ToUpper(entry.Algorithm)
+	if algorithm == "" {
+		algorithm = "SHA1"
+	}
+	p.Algorithm = pb.MigrationPayload_ALGO_INVALID
+	for value, name := range migrationAlgorithms {
+		if name == algorithm {
+			p.Algorithm = value
+		}
+	}
+	if p.Algorithm == pb.MigrationPayload_ALGO_INVALID {
+		return nil, fmt.Errorf("不支持的算法: %s", entry.Algorithm)
+	}
+
+	digits := entry.Digits
+	if digits == 0 {
+		digits = 6
+	}
+	for value, count := range migrationDigits {
+		if count == digits {
+			p.Digits = value
+		}
+	}
+	if p.Digits == 0 {
+		return nil, fmt.Errorf("谷歌验证器不支持 %d 位验证码", entry.Digits)
+	}
+
+	return p, nil
+}
+
+func encodeMigrationURL(payload *pb.MigrationPayload) (string, error) {
+	rawData, err := proto.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("Protobuf编码失败: %v", err)
+	}
+
+	params := url.Values{}
+	params.Set("data", base64.StdEncoding.EncodeToString(rawData))
+	return "otpauth-migration://offline?" + params.Encode(), nil
+}
+
+// randomBatchID 谷歌验证器用 batch_id 识别属于同一次导出的多个二维码
+func randomBatchID() (int32, error) {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b[:]) & 0x7FFFFFFF), nil
+}
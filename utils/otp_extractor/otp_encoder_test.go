@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"encoding/base32"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testEntries 生成 n 个账户，名称补齐到 nameLen 个字符，用于控制链接长度
+func testEntries(n int, nameLen int) []OtpEntry {
+	entries := make([]OtpEntry, 0, n)
+	for i := 0; i < n; i++ {
+		secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte(fmt.Sprintf("secret-%03d-0123456789", i)))
+		name := fmt.Sprintf("user%03d", i)
+		if len(name) < nameLen {
+			name += strings.Repeat("x", nameLen-len(name))
+		}
+		entries = append(entries, OtpEntry{Name: name, Issuer: "Example", Secret: secret, Type: "totp", Algorithm: "SHA1", Digits: 6, Period: 30})
+	}
+	return entries
+}
+
+func TestBuildMigrationURLsRoundTrip(t *testing.T) {
+	entries := []OtpEntry{
+		{Name: "alice", Issuer: "GitHub", Secret: "JBSWY3DPEHPK3PXP", Type: "totp", Algorithm: "SHA1", Digits: 6, Period: 30},
+		{Name: "bob", Issuer: "Example", Secret: "gezd gnbv gy3t qojq", Type: "hotp", Algorithm: "sha256", Digits: 8, Counter: 5},
+		{Name: "carol", Secret: "KRSXG5CTMVRXEZLU", Type: "totp"},
+	}
+
+	urls, err := BuildMigrationURLs(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 1 {
+		t.Fatalf("生成了 %d 个链接，期望 1 个", len(urls))
+	}
+
+	decoded, err := ExtractOtpFromUrl(urls[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []OtpEntry{
+		{Name: "alice", Issuer: "GitHub", Secret: "JBSWY3DPEHPK3PXP", Type: "totp", Algorithm: "SHA1", Digits: 6, Period: 30},
+		{Name: "bob", Issuer: "Example", Secret: "GEZDGNBVGY3TQOJQ", Type: "hotp", Algorithm: "SHA256", Digits: 8, Period: 30, Counter: 5},
+		{Name: "carol", Secret: "KRSXG5CTMVRXEZLU", Type: "totp", Algorithm: "SHA1", Digits: 6, Period: 30},
+	}
+	if len(decoded) != len(want) {
+		t.Fatalf("解析出 %d 个账户", len(decoded))
+	}
+	for i := range want {
+		decoded[i].URL = ""
+		if decoded[i] != want[i] {
+			t.Errorf("第 %d 个账户为 %+v，期望 %+v", i, decoded[i], want[i])
+		}
+	}
+}
+
+func TestBuildMigrationURLsBatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []OtpEntry
+		batches int
+	}{
+		{"正好 10 个", testEntries(10, 0), 1},
+		{"11 个", testEntries(11, 0), 2},
+		{"25 个", testEntries(25, 0), 3},
+		// 每个账户约 200 字节，未满 10 个时就会超过长度上限
+		{"名称较长", testEntries(10, 150), 3},
+		{"单个账户超过长度上限", testEntries(2, migrationMaxURLLength), 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls, err := BuildMigrationURLs(tt.entries)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(urls) != tt.batches {
+				t.Fatalf("生成了 %d 个链接，期望 %d 个", len(urls), tt.batches)
+			}
+
+			var decoded []OtpEntry
+			var batchID int32
+			for i, u := range urls {
+				batch, err := ExtractMigrationBatch(u)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(batch.Entries) > migrationMaxEntries {
+					t.Errorf("第 %d 个链接包含 %d 个账户", i, len(batch.Entries))
+				}
+				// 只有单个账户本身就超长时才允许超过长度上限
+				if len(u) > migrationMaxURLLength && len(batch.Entries) > 1 {
+					t.Errorf("第 %d 个链接长度为 %d", i, len(u))
+				}
+				if i == 0 {
+					batchID = batch.ID
+				}
+				if batch.ID != batchID || batch.Index != int32(i) || batch.Size != int32(len(urls)) {
+					t.Errorf("第 %d 个链接的批次信息为 %d/%d/%d", i, batch.ID, batch.Index, batch.Size)
+				}
+				decoded = append(decoded, batch.Entries...)
+			}
+
+			if len(decoded) != len(tt.entries) {
+				t.Fatalf("共解析出 %d 个账户，期望 %d 个", len(decoded), len(tt.entries))
+			}
+			for i, entry := range tt.entries {
+				if decoded[i].Name != entry.Name || decoded[i].Secret != entry.Secret {
+					t.Errorf("第 %d 个账户为 %s，期望 %s", i, decoded[i].Name, entry.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildMigrationURLsUnsupported(t *testing.T) {
+	valid := OtpEntry{Name: "alice", Secret: "JBSWY3DPEHPK3PXP", Type: "totp"}
+
+	tests := []struct {
+		name   string
+		modify func(e *OtpEntry)
+	}{
+		{"Steam", func(e *OtpEntry) { e.Type = "steam" }},
+		{"60 秒周期", func(e *OtpEntry) { e.Period = 60 }},
+		{"7 位验证码", func(e *OtpEntry) { e.Digits = 7 }},
+		{"未知算法", func(e *OtpEntry) { e.Algorithm = "SHA3" }},
+		{"密钥不是 Base32", func(e *OtpEntry) { e.Secret = "not base32!" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := valid
+			tt.modify(&entry)
+			if _, err := BuildMigrationURLs([]OtpEntry{valid, entry}); err == nil {
+				t.Error("应返回错误")
+			}
+		})
+	}
+
+	if _, err := BuildMigrationURLs(nil); err == nil {
+		t.Error("没有账户时应返回错误")
+	}
+}
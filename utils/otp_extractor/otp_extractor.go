@@ -13,31 +13,84 @@ import (
 
 // 创建 OTP 导出的结构体
 type OtpEntry struct {
-	Name    string `json:"name"`
-	Secret  string `json:"secret"`
-	Issuer  string `json:"issuer"`
-	Type    string `json:"type"`
-	Counter int64  `json:"counter,omitempty"`
-	URL     string `json:"url"`
+	Name      string `json:"name"`
+	Secret    string `json:"secret"`
+	Issuer    string `json:"issuer"`
+	Type      string `json:"type"`
+	Algorithm string `json:"algorithm"`
+	Digits    int    `json:"digits"`
+	Period    int    `json:"period"`
+	Counter   int64  `json:"counter,omitempty"`
+	URL       string `json:"url"`
+}
+
+// 谷歌验证器导出格式中没有周期字段，固定为 30 秒
+const migrationPeriod = 30
+
+// 生成的 proto 代码只声明了 SHA1，其余取值参照谷歌验证器的定义
+var migrationAlgorithms = map[pb.MigrationPayload_Algorithm]string{
+	pb.MigrationPayload_ALGO_SHA1: "SHA1",
+	2:                             "SHA256",
+	3:                             "SHA512",
+	4:                             "MD5",
+}
+
+// 导出格式中的 digits 字段是枚举：1 表示 6 位，2 表示 8 位
+var migrationDigits = map[int32]int{
+	1: 6,
+	2: 8,
+}
+
+// migrationAlgorithm 返回导出数据中算法对应的名称，未指定时为 SHA1
+func migrationAlgorithm(algorithm pb.MigrationPayload_Algorithm) string {
+	if name, ok := migrationAlgorithms[algorithm]; ok {
+		return name
+	}
+	return "SHA1"
+}
+
+// migrationDigitCount 返回导出数据中的验证码位数，未指定时为 6 位
+func migrationDigitCount(digits int32) int {
+	if count, ok := migrationDigits[digits]; ok {
+		return count
+	}
+	return 6
+}
+
+// MigrationBatch 一张迁移二维码的内容，谷歌验证器导出多张二维码时它们的 ID 相同，Index 从 0 开始
+type MigrationBatch struct {
+	ID      int32
+	Index   int32
+	Size    int32
+	Entries []OtpEntry
 }
 
 // 从URL提取OTP信息
 func ExtractOtpFromUrl(otpURL string) ([]OtpEntry, error) {
+	batch, err := ExtractMigrationBatch(otpURL)
+	if err != nil {
+		return nil, err
+	}
+	return batch.Entries, nil
+}
+
+// ExtractMigrationBatch 解析迁移链接，同时返回其在整组导出二维码中的位置
+func ExtractMigrationBatch(otpURL string) (MigrationBatch, error) {
 	// 解析URL
 	parsedURL, err := url.Parse(otpURL)
 	if err != nil {
-		return nil, fmt.Errorf("URL解析失败: %v", err)
+		return MigrationBatch{}, fmt.Errorf("URL解析失败: %v", err)
 	}
 
 	// 获取data参数
 	queryParams, err := url.ParseQuery(parsedURL.RawQuery)
 	if err != nil {
-		return nil, fmt.Errorf("解析查询参数失败: %v", err)
+		return MigrationBatch{}, fmt.Errorf("解析查询参数失败: %v", err)
 	}
 
 	dataBase64 := queryParams.Get("data")
 	if dataBase64 == "" {
-		return nil, fmt.Errorf("URL中没有data参数")
+		return MigrationBatch{}, fmt.Errorf("URL中没有data参数")
 	}
 
 	// 替换空格为+，修正可能的base64编码问题
@@ -46,13 +99,13 @@ func ExtractOtpFromUrl(otpURL string) ([]OtpEntry, error) {
 	// base64解码
 	rawData, err := base64.StdEncoding.DecodeString(dataBase64)
 	if err != nil {
-		return nil, fmt.Errorf("Base64解码失败: %v", err)
+		return MigrationBatch{}, fmt.Errorf("Base64解码失败: %v", err)
 	}
 
 	// 解析protobuf - 使用生成的代码
 	payload := &pb.MigrationPayload{}
 	if err := proto.Unmarshal(rawData, payload); err != nil {
-		return nil, fmt.Errorf("Protobuf解析失败: %v", err)
+		return MigrationBatch{}, fmt.Errorf("Protobuf解析失败: %v", err)
 	}
 
 	// 提取OTP信息
@@ -72,11 +125,14 @@ func ExtractOtpFromUrl(otpURL string) ([]OtpEntry, error) {
 		otpAuthURL := BuildOtpURL(secret, otp)
 
 		entry := OtpEntry{
-			Name:   otp.Name,
-			Secret: secret,
-			Issuer: otp.Issuer,
-			Type:   otpType,
-			URL:    otpAuthURL,
+			Name:      otp.Name,
+			Secret:    secret,
+			Issuer:    otp.Issuer,
+			Type:      otpType,
+			Algorithm: migrationAlgorithm(otp.Algorithm),
+			Digits:    migrationDigitCount(otp.Digits),
+			Period:    migrationPeriod,
+			URL:       otpAuthURL,
 		}
 
 		if otp.Type == pb.MigrationPayload_OTP_HOTP {
@@ -86,7 +142,12 @@ func ExtractOtpFromUrl(otpURL string) ([]OtpEntry, error) {
 		entries = append(entries, entry)
 	}
 
-	return entries, nil
+	return MigrationBatch{
+		ID:      payload.GetBatchId(),
+		Index:   payload.GetBatchIndex(),
+		Size:    payload.GetBatchSize(),
+		Entries: entries,
+	}, nil
 }
 
 // 构建标准otpauth URL
@@ -101,6 +162,8 @@ func BuildOtpURL(secret string, otp *pb.MigrationPayload_OtpParameters) string {
 	if otp.Issuer != "" {
 		params.Set("issuer", otp.Issuer)
 	}
+	params.Set("algorithm", migrationAlgorithm(otp.Algorithm))
+	params.Set("digits", fmt.Sprintf("%d", migrationDigitCount(otp.Digits)))
 	if otp.Type == pb.MigrationPayload_OTP_HOTP {
 		params.Set("counter", fmt.Sprintf("%d", otp.Counter))
 	}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"bytes"
+	"image/png"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// qrCodeSize 生成的二维码图片边长（像素）
+const qrCodeSize = 400
+
+// EncodeQRCodePNG 把文本编码为 PNG 格式的二维码图片
+func EncodeQRCodePNG(text string) ([]byte, error) {
+	hints := map[gozxing.EncodeHintType]interface{}{
+		gozxing.EncodeHintType_ERROR_CORRECTION: "M",
+	}
+
+	matrix, err := qrcode.NewQRCodeWriter().Encode(text, gozxing.BarcodeFormat_QR_CODE, qrCodeSize, qrCodeSize, hints)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, matrix); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
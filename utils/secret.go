@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"auth/apperr"
+	"encoding/base32"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// steamSecretSize Steam 的 shared_secret 是 20 字节的 HMAC-SHA1 密钥
+const steamSecretSize = 20
+
+const base32Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+
+// stripWhitespace 去掉用户复制密钥时常带的空格和换行
+func stripWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
+
+// NormalizeBase32Secret 规范化 TOTP/HOTP 密钥：去掉空白和分隔符、转为大写、去掉填充，并检查能否解码
+func NormalizeBase32Secret(secret string) (string, error) {
+	// 部分网站以 "ABCD-EFGH" 的形式分组展示密钥
+	secret = strings.ReplaceAll(stripWhitespace(secret), "-", "")
+	secret = strings.TrimRight(strings.ToUpper(secret), "=")
+	if secret == "" {
+		return "", apperr.New(apperr.InvalidSecret, "密钥不能为空")
+	}
+
+	for i, r := range secret {
+		if !strings.ContainsRune(base32Alphabet, r) {
+			return "", apperr.New(apperr.InvalidSecret, fmt.Sprintf("密钥第 %d 个字符 %q 不是有效的 Base32 字符", i+1, r))
+		}
+	}
+
+	// 每 8 个字符对应 5 字节，余 1、3、6 个字符时不可能是合法编码
+	switch len(secret) % 8 {
+	case 1, 3, 6:
+		return "", apperr.New(apperr.InvalidSecret, "密钥长度不正确，可能缺少或多出字符")
+	}
+
+	if _, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret); err != nil {
+		return "", apperr.Wrap(apperr.InvalidSecret, "密钥 Base32 解码失败", err)
+	}
+
+	return secret, nil
+}
+
+// NormalizeSteamSecret 规范化 Steam 的 shared_secret：去掉空白，兼容 URL 安全字符和缺失的填充，统一为标准 Base64
+func NormalizeSteamSecret(secret string) (string, error) {
+	secret = strings.TrimRight(stripWhitespace(secret), "=")
+	if secret == "" {
+		return "", apperr.New(apperr.InvalidSecret, "密钥不能为空")
+	}
+	secret = strings.NewReplacer("-", "+", "_", "/").Replace(secret)
+
+	raw, err := base64.RawStdEncoding.DecodeString(secret)
+	if err != nil {
+		return "", apperr.Wrap(apperr.InvalidSecret, "Steam 密钥 Base64 解码失败", err)
+	}
+	if len(raw) != steamSecretSize {
+		return "", apperr.New(apperr.InvalidSecret, fmt.Sprintf("Steam 密钥应为 %d 字节，实际为 %d 字节", steamSecretSize, len(raw)))
+	}
+
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
@@ -9,6 +9,9 @@ import (
 	"time"
 )
 
+// SteamPeriod Steam Guard 验证码的有效周期（秒），不可配置
+const SteamPeriod = 30
+
 // Steam TOTP 可用的字符集
 var steamChars = []byte("23456789BCDFGHJKMNPQRTVWXY")
 
@@ -30,7 +33,7 @@ func GenerateCodeWithTime(sharedSecret string, t time.Time) (string, error) {
 	}
 
 	// 时间戳，单位30秒
-	timeSlice := uint64(t.Unix() / 30)
+	timeSlice := uint64(t.Unix() / SteamPeriod)
 
 	// 把时间戳转成8字节 big-endian
 	timeBytes := make([]byte, 8)
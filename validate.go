@@ -0,0 +1,58 @@
+package main
+
+import (
+	"auth/apperr"
+	"auth/logger"
+	"auth/model"
+	"auth/utils"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// normalizeSecret 校验并规范化待保存的账户，返回规范化后的账户和明文密钥
+// 保存前试生成一次验证码，避免写入之后每次刷新都静默失败的密钥
+func normalizeSecret(secret model.Secret, plaintext string) (model.Secret, string, error) {
+	logger.Protect(plaintext)
+	secret.AccountName = strings.TrimSpace(secret.AccountName)
+	secret.ServerName = strings.TrimSpace(secret.ServerName)
+	if secret.AccountName == "" {
+		return secret, "", apperr.New(apperr.InvalidArgument, "账户名称不能为空")
+	}
+
+	if secret.Algorithm == "" {
+		secret.Algorithm = model.DefaultAlgorithm
+	}
+	secret.Algorithm = strings.ToUpper(secret.Algorithm)
+	if _, err := utils.ParseAlgorithm(secret.Algorithm); err != nil {
+		return secret, "", apperr.Wrap(apperr.Unsupported, "不支持的算法", err)
+	}
+	if secret.Digits == 0 {
+		secret.Digits = model.DefaultDigits
+	}
+	if secret.Period == 0 {
+		secret.Period = model.DefaultPeriod
+	}
+
+	var err error
+	switch secret.AccountType {
+	case model.AccountTypeTOTP, model.AccountTypeHOTP:
+		if secret.Digits < 6 || secret.Digits > 8 {
+			return secret, "", apperr.New(apperr.InvalidArgument, fmt.Sprintf("验证码位数应为 6 到 8 位，实际为 %d 位", secret.Digits))
+		}
+		plaintext, err = utils.NormalizeBase32Secret(plaintext)
+	case model.AccountTypeSteam:
+		plaintext, err = utils.NormalizeSteamSecret(plaintext)
+	default:
+		return secret, "", apperr.New(apperr.Unsupported, fmt.Sprintf("未知的账户类型: %d", secret.AccountType))
+	}
+	if err != nil {
+		return secret, "", err
+	}
+
+	if _, err := generateCode(secret, plaintext, time.Now()); err != nil {
+		return secret, "", apperr.Wrap(apperr.InvalidSecret, "无法用该密钥生成验证码", err)
+	}
+
+	return secret, plaintext, nil
+}